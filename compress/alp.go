@@ -0,0 +1,244 @@
+package compress
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"math/bits"
+
+	"github.com/smpanaro/time-series-compression/series"
+)
+
+// ALP is based on "ALP: Adaptive Lossless floating-Point Compression"
+// (Afroozeh et al., 2023). Decimal values like 17.52 are stored as integers
+// by searching each block for the exponent e and factor f where
+//
+//	d = round(v * 10^e / 10^f)
+//	v = d * 10^f / 10^e
+//
+// round trips for the most values. The integers are bit packed with
+// frame-of-reference and values that do not round trip are stored as
+// exceptions. Timestamps are delta-of-delta encoded.
+//
+// Each block is written as:
+//
+//	e, f      8 bits each
+//	width     8 bits, bits per packed integer
+//	base      64 bits, the frame of reference
+//	count     16 bits, number of exceptions
+//	packed    width bits per value
+//	exception 16 bit position + 64 bit value, for each exception
+const (
+	alpBlockSize   = 1024
+	alpMaxExponent = 18
+
+	alpExceptionBits = 16 + 64
+)
+
+var alpPow10 = [alpMaxExponent + 1]float64{
+	1, 1e1, 1e2, 1e3, 1e4, 1e5, 1e6, 1e7, 1e8, 1e9,
+	1e10, 1e11, 1e12, 1e13, 1e14, 1e15, 1e16, 1e17, 1e18,
+}
+
+func alpEncode(v float64, e, f int) (int64, bool) {
+	scaled := v * alpPow10[e] / alpPow10[f]
+	if math.IsNaN(scaled) || math.Abs(scaled) > 1<<52 {
+		return 0, false
+	}
+	d := int64(math.Round(scaled))
+	return d, math.Float64bits(alpDecode(d, e, f)) == math.Float64bits(v)
+}
+
+func alpDecode(d int64, e, f int) float64 {
+	return float64(d) * alpPow10[f] / alpPow10[e]
+}
+
+// alpBlock is a block of values encoded with one exponent and factor.
+type alpBlock struct {
+	e, f       int
+	encoded    []int64
+	base       int64
+	width      int
+	exceptions []int
+}
+
+func newALPBlock(values []float64, e, f int) alpBlock {
+	b := alpBlock{e: e, f: f, encoded: make([]int64, len(values))}
+	filled := false
+	for i, v := range values {
+		d, ok := alpEncode(v, e, f)
+		if !ok {
+			b.exceptions = append(b.exceptions, i)
+			continue
+		}
+		b.encoded[i] = d
+		if !filled {
+			// Fill exceptions with an encodable value so they do not widen the frame.
+			for _, j := range b.exceptions {
+				b.encoded[j] = d
+			}
+			filled = true
+		}
+	}
+	if filled {
+		for _, j := range b.exceptions {
+			if j > 0 {
+				b.encoded[j] = b.encoded[j-1]
+			}
+		}
+	}
+
+	minimum, maximum := b.encoded[0], b.encoded[0]
+	for _, d := range b.encoded {
+		if d < minimum {
+			minimum = d
+		}
+		if d > maximum {
+			maximum = d
+		}
+	}
+	b.base = minimum
+	b.width = bits.Len64(uint64(maximum) - uint64(minimum))
+	return b
+}
+
+func (b alpBlock) size() int {
+	return len(b.encoded)*b.width + len(b.exceptions)*alpExceptionBits
+}
+
+func writeALPBlock(w *bitWriter, values []float64) {
+	best := newALPBlock(values, 0, 0)
+	for e := 0; e <= alpMaxExponent; e++ {
+		for f := 0; f <= e; f++ {
+			if b := newALPBlock(values, e, f); b.size() < best.size() {
+				best = b
+			}
+		}
+	}
+
+	w.writeBits(uint64(best.e), 8)
+	w.writeBits(uint64(best.f), 8)
+	w.writeBits(uint64(best.width), 8)
+	w.writeBits(uint64(best.base), 64)
+	w.writeBits(uint64(len(best.exceptions)), 16)
+	for _, d := range best.encoded {
+		w.writeBits(uint64(d)-uint64(best.base), best.width)
+	}
+	for _, i := range best.exceptions {
+		w.writeBits(uint64(i), 16)
+		w.writeBits(math.Float64bits(values[i]), 64)
+	}
+}
+
+func readALPBlock(r *bitReader, values []float64) error {
+	var header [5]uint64
+	for i, n := range []int{8, 8, 8, 64, 16} {
+		v, err := r.readBits(n)
+		if err != nil {
+			return err
+		}
+		header[i] = v
+	}
+	e, f, width, base, exceptions := int(header[0]), int(header[1]), int(header[2]), header[3], int(header[4])
+	if e > alpMaxExponent || f > e || width > 64 || exceptions > len(values) {
+		return fmt.Errorf("invalid alp block header: e=%d f=%d width=%d exceptions=%d", e, f, width, exceptions)
+	}
+
+	for i := range values {
+		v, err := r.readBits(width)
+		if err != nil {
+			return err
+		}
+		values[i] = alpDecode(int64(base+v), e, f)
+	}
+	for i := 0; i < exceptions; i++ {
+		pos, err := r.readBits(16)
+		if err != nil {
+			return err
+		}
+		v, err := r.readBits(64)
+		if err != nil {
+			return err
+		}
+		if int(pos) >= len(values) {
+			return fmt.Errorf("invalid alp exception position: %d", pos)
+		}
+		values[pos] = math.Float64frombits(v)
+	}
+	return nil
+}
+
+// compressALP writes the point count, the length of the timestamp section,
+// the timestamp section and then each block of values.
+func (c *Compressor) compressALP(points series.Points) ([]byte, error) {
+	buckets := c.timestampBuckets()
+	if err := validateTimestampBuckets(buckets); err != nil {
+		return nil, err
+	}
+
+	tw := &bitWriter{buf: appendTimestampBuckets(nil, buckets)}
+	times := &timestampEncoder{w: tw, buckets: buckets}
+	values := make([]float64, len(points))
+	for i, pt := range points {
+		times.write(pt.TimeUnix(c.opts.resolution()))
+		values[i] = pt.Value
+	}
+
+	buf := binary.AppendUvarint(nil, uint64(len(points)))
+	buf = binary.AppendUvarint(buf, uint64(len(tw.bytes())))
+	w := &bitWriter{buf: append(buf, tw.bytes()...)}
+	for start := 0; start < len(values); start += alpBlockSize {
+		end := start + alpBlockSize
+		if end > len(values) {
+			end = len(values)
+		}
+		writeALPBlock(w, values[start:end])
+	}
+	return w.bytes(), nil
+}
+
+func (c *Compressor) decompressALP(b []byte) (series.Points, error) {
+	count, n := binary.Uvarint(b)
+	if n <= 0 {
+		return nil, fmt.Errorf("invalid point count")
+	}
+	b = b[n:]
+	timesLen, n := binary.Uvarint(b)
+	if n <= 0 || timesLen > uint64(len(b)-n) {
+		return nil, fmt.Errorf("invalid timestamp length")
+	}
+	b = b[n:]
+	if count > timesLen*8 {
+		return nil, fmt.Errorf("invalid point count: %d", count)
+	}
+
+	buckets, timesBuf, err := readTimestampBuckets(b[:timesLen])
+	if err != nil {
+		return nil, err
+	}
+	times := &timestampDecoder{r: &bitReader{buf: timesBuf}, buckets: buckets}
+	points := make(series.Points, count)
+	for i := range points {
+		t, err := times.read()
+		if err != nil {
+			return nil, err
+		}
+		points[i] = &series.Point{Time: c.opts.resolution().Time(t)}
+	}
+
+	r := &bitReader{buf: b[timesLen:]}
+	values := make([]float64, alpBlockSize)
+	for start := 0; start < len(points); start += alpBlockSize {
+		block := points[start:]
+		if len(block) > alpBlockSize {
+			block = block[:alpBlockSize]
+		}
+		if err := readALPBlock(r, values[:len(block)]); err != nil {
+			return nil, err
+		}
+		for i, pt := range block {
+			pt.Value = values[i]
+		}
+	}
+	return points, nil
+}
@@ -0,0 +1,51 @@
+package compress
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/smpanaro/time-series-compression/series"
+	"github.com/stretchr/testify/require"
+)
+
+func TestALPBlock(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	values := []float64{0, 17.52, -3.25, math.Inf(-1), math.NaN(), math.MaxFloat64, math.SmallestNonzeroFloat64}
+	for len(values) < alpBlockSize {
+		values = append(values, math.Round(rng.NormFloat64()*1000)/100)
+	}
+
+	w := &bitWriter{}
+	writeALPBlock(w, values)
+
+	got := make([]float64, len(values))
+	require.NoError(t, readALPBlock(&bitReader{buf: w.bytes()}, got))
+	for i, v := range values {
+		require.Equal(t, math.Float64bits(v), math.Float64bits(got[i]), "index %d", i)
+	}
+
+	// Two decimal places should be found and the special values kept as exceptions.
+	best := newALPBlock(values, 2, 0)
+	require.Len(t, best.exceptions, 4)
+}
+
+func TestCompressor_ALP(t *testing.T) {
+	var points series.Points
+	for i := 0; i < 2*alpBlockSize+10; i++ {
+		points = append(points, &series.Point{Time: time.UnixMilli(int64(1691161006379 + i*15)), Value: float64(i%300) / 10})
+	}
+	points[5].Value = math.Pi
+
+	c := NewCompressorOptions(Options{Method: ALP, Verify: VerifyStrict})
+	enc, err := c.Compress(points)
+	require.NoError(t, err)
+
+	dec, err := Decompress(enc)
+	require.NoError(t, err)
+	require.True(t, points.Equal(dec))
+
+	_, err = NewCompressor(ALP).Decompress(enc[:len(enc)-8])
+	require.Error(t, err)
+}
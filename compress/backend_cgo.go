@@ -0,0 +1,104 @@
+//go:build cgo && !purego
+
+package compress
+
+/*
+#cgo darwin LDFLAGS: -L/opt/homebrew/lib
+#cgo LDFLAGS: -lbrotlicommon
+*/
+import "C"
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+
+	"github.com/DataDog/zstd"
+	"github.com/blacktop/lzfse-cgo"
+	"github.com/danielrh/go-xz"
+	"github.com/google/brotli/go/cbrotli"
+)
+
+// The reference C implementations of zstd, brotli, lzfse and xz. Build with
+// -tags purego or CGO_ENABLED=0 to use backend_purego.go instead.
+
+const lzfseAvailable = true
+
+func (c *Compressor) compressZstd(b *bytes.Buffer) ([]byte, error) {
+	// Use this since it wraps the official implementation (vs. a native go implementation).
+	return zstd.CompressLevel(nil, b.Bytes(), c.level(zstdLevels))
+}
+
+func (c *Compressor) decompressZstd(b []byte) ([]byte, error) {
+	return zstd.Decompress(nil, b)
+}
+
+func (c *Compressor) compressZstdDict(b *bytes.Buffer, d *Dictionary) ([]byte, error) {
+	if d == nil {
+		return c.compressZstd(b)
+	}
+	p, err := zstd.NewBulkProcessor(d.Content, c.level(zstdLevels))
+	if err != nil {
+		return nil, err
+	}
+	return p.Compress(nil, b.Bytes())
+}
+
+func (c *Compressor) decompressZstdDict(b []byte, d *Dictionary) ([]byte, error) {
+	if d == nil {
+		return c.decompressZstd(b)
+	}
+	p, err := zstd.NewBulkProcessor(d.Content, c.level(zstdLevels))
+	if err != nil {
+		return nil, err
+	}
+	return p.Decompress(nil, b)
+}
+
+// CPATH=/opt/homebrew/include CGO_LDFLAGS="-L/opt/homebrew/lib -lbrotlicommon" go run . evaluate -a brotli-csv -p fixtures/brew1.txt
+func (c *Compressor) compressBrotli(b *bytes.Buffer) ([]byte, error) {
+	return cbrotli.Encode(b.Bytes(), cbrotli.WriterOptions{
+		Quality: c.level(brotliLevels),
+	})
+}
+
+func (c *Compressor) decompressBrotli(b []byte) ([]byte, error) {
+	return cbrotli.Decode(b)
+}
+
+func (c *Compressor) compressLzfse(b *bytes.Buffer) ([]byte, error) {
+	// lzfse needs room for its block headers even when the input is only a
+	// few bytes.
+	encLen := b.Len()*2 + 64
+	enc := make([]byte, encLen)
+	written := lzfse.EncodeBuffer(enc, uint(encLen), b.String(), uint(b.Len()), nil)
+	if written == 0 {
+		return nil, fmt.Errorf("compression failed")
+	}
+	return enc[:written], nil
+}
+
+func (c *Compressor) decompressLzfse(b []byte) []byte {
+	return lzfse.DecodeBuffer(b)
+}
+
+func (c *Compressor) compressLzma(b *bytes.Buffer) ([]byte, error) {
+	comp := bytes.NewBuffer(nil)
+	w := xz.NewCompressionWriterPreset(comp, c.level(lzmaLevels))
+	_, err := w.Write(b.Bytes())
+	if err != nil {
+		return nil, err
+	}
+
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return comp.Bytes(), nil
+}
+
+func (c *Compressor) decompressLzma(b []byte) ([]byte, error) {
+	r := xz.NewDecompressionReader(bytes.NewBuffer(b))
+	defer r.Close()
+
+	return io.ReadAll(&r)
+}
@@ -0,0 +1,111 @@
+//go:build !cgo || purego
+
+package compress
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+
+	"github.com/andybalholm/brotli"
+	"github.com/klauspost/compress/zstd"
+	"github.com/ulikunitz/xz"
+)
+
+// Pure Go implementations of zstd, brotli and xz that build without cgo or
+// Homebrew. Their output is compatible with the C implementations in
+// backend_cgo.go but not byte for byte identical, so sizes differ slightly.
+// There is no pure Go lzfse encoder, so LzfseCSV is not registered.
+
+const lzfseAvailable = false
+
+var errNoLzfse = fmt.Errorf("lzfse requires cgo")
+
+func (c *Compressor) compressZstd(b *bytes.Buffer) ([]byte, error) {
+	return c.compressZstdDict(b, nil)
+}
+
+func (c *Compressor) decompressZstd(b []byte) ([]byte, error) {
+	return c.decompressZstdDict(b, nil)
+}
+
+func (c *Compressor) compressZstdDict(b *bytes.Buffer, d *Dictionary) ([]byte, error) {
+	opts := []zstd.EOption{zstd.WithEncoderLevel(zstd.EncoderLevelFromZstd(c.level(zstdLevels)))}
+	if d != nil {
+		opts = append(opts, zstd.WithEncoderDict(d.Content))
+	}
+	w, err := zstd.NewWriter(nil, opts...)
+	if err != nil {
+		return nil, err
+	}
+	defer w.Close()
+	return w.EncodeAll(b.Bytes(), nil), nil
+}
+
+func (c *Compressor) decompressZstdDict(b []byte, d *Dictionary) ([]byte, error) {
+	var opts []zstd.DOption
+	if d != nil {
+		opts = append(opts, zstd.WithDecoderDicts(d.Content))
+	}
+	r, err := zstd.NewReader(nil, opts...)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	return r.DecodeAll(b, nil)
+}
+
+func (c *Compressor) compressBrotli(b *bytes.Buffer) ([]byte, error) {
+	var buf bytes.Buffer
+	w := brotli.NewWriterLevel(&buf, c.level(brotliLevels))
+	if _, err := w.Write(b.Bytes()); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (c *Compressor) decompressBrotli(b []byte) ([]byte, error) {
+	return io.ReadAll(brotli.NewReader(bytes.NewReader(b)))
+}
+
+func (c *Compressor) compressLzfse(b *bytes.Buffer) ([]byte, error) {
+	return nil, errNoLzfse
+}
+
+func (c *Compressor) decompressLzfse(b []byte) []byte {
+	return nil
+}
+
+// xzPresetDictCaps are the dictionary sizes of xz presets 0 to 9.
+var xzPresetDictCaps = [...]int{
+	256 << 10, 1 << 20, 2 << 20, 4 << 20, 4 << 20,
+	8 << 20, 8 << 20, 16 << 20, 32 << 20, 64 << 20,
+}
+
+func (c *Compressor) compressLzma(b *bytes.Buffer) ([]byte, error) {
+	var buf bytes.Buffer
+	// ulikunitz/xz has no presets, so use the dictionary size of each one.
+	config := xz.WriterConfig{DictCap: xzPresetDictCaps[c.level(lzmaLevels)]}
+	w, err := config.NewWriter(&buf)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := w.Write(b.Bytes()); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (c *Compressor) decompressLzma(b []byte) ([]byte, error) {
+	r, err := xz.NewReader(bytes.NewReader(b))
+	if err != nil {
+		return nil, err
+	}
+	return io.ReadAll(r)
+}
@@ -0,0 +1,186 @@
+package compress
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+
+	"github.com/smpanaro/time-series-compression/series"
+)
+
+// fixedMethods are the methods that use fixedLayout and support
+// Options.Shuffle.
+var fixedMethods = Methods{ZstdFixed, GzipFixed, ZlibFixed, BrotliFixed, LzfseFixed, LzmaFixed, Lz4Fixed, SnappyFixed}
+
+func validateShuffle(opts Options) error {
+	if _, ok := shuffleNames[opts.Shuffle]; !ok {
+		return fmt.Errorf("unsupported shuffle: %s", opts.Shuffle)
+	}
+	if opts.Shuffle != ShuffleNone && !fixedMethods.Contains(opts.Method) {
+		return fmt.Errorf("%s does not support shuffle. use one of: %s", opts.Method, fixedMethods.Join(", "))
+	}
+	return nil
+}
+
+// binaryLayout is how the flattened integers are written.
+type binaryLayout int
+
+const (
+	// varintLayout writes each integer as a uvarint.
+	varintLayout binaryLayout = iota
+	// fixedLayout writes each integer as a little endian uint64.
+	fixedLayout
+)
+
+// backend is a general purpose byte compressor.
+type backend struct {
+	compress   func(*Compressor, *bytes.Buffer) ([]byte, error)
+	decompress func(*Compressor, []byte) ([]byte, error)
+}
+
+func (c *Compressor) decompressLzfseBytes(b []byte) ([]byte, error) {
+	decomp := c.decompressLzfse(b)
+	if decomp == nil {
+		return nil, fmt.Errorf("decompression failed")
+	}
+	return decomp, nil
+}
+
+// binaryCodecs returns a codec for every backend and binary layout. They give
+// the general purpose backends the same delta encoded integers as simple-8b
+// (see Compressor.flatten) instead of the delta CSV, to compare text and
+// binary intermediate representations.
+func binaryCodecs() []compressorCodec {
+	backends := []struct {
+		varint, fixed Method
+		backend       backend
+	}{
+		{ZstdVarint, ZstdFixed, backend{(*Compressor).compressZstd, (*Compressor).decompressZstd}},
+		{GzipVarint, GzipFixed, backend{(*Compressor).compressGzip, (*Compressor).decompressGzip}},
+		{ZlibVarint, ZlibFixed, backend{(*Compressor).compressZlib, (*Compressor).decompressZlib}},
+		{BrotliVarint, BrotliFixed, backend{(*Compressor).compressBrotli, (*Compressor).decompressBrotli}},
+		{LzfseVarint, LzfseFixed, backend{(*Compressor).compressLzfse, (*Compressor).decompressLzfseBytes}},
+		{LzmaVarint, LzmaFixed, backend{(*Compressor).compressLzma, (*Compressor).decompressLzma}},
+		{Lz4Varint, Lz4Fixed, backend{(*Compressor).compressLz4, (*Compressor).decompressLz4}},
+		{SnappyVarint, SnappyFixed, backend{(*Compressor).compressSnappy, (*Compressor).decompressSnappy}},
+	}
+
+	var codecs []compressorCodec
+	for _, b := range backends {
+		if b.varint == LzfseVarint && !lzfseAvailable {
+			continue
+		}
+		codecs = append(codecs,
+			compressorCodec{b.varint, varintLayout.compress(b.backend), varintLayout.decompress(b.backend)},
+			compressorCodec{b.fixed, fixedLayout.compress(b.backend), fixedLayout.decompress(b.backend)},
+		)
+	}
+	return codecs
+}
+
+func (l binaryLayout) compress(b backend) func(*Compressor, series.Points) ([]byte, error) {
+	return func(c *Compressor, points series.Points) ([]byte, error) {
+		buf, err := c.binary(points, l)
+		if err != nil {
+			return nil, err
+		}
+		return b.compress(c, buf)
+	}
+}
+
+func (l binaryLayout) decompress(b backend) func(*Compressor, []byte) (series.Points, error) {
+	return func(c *Compressor, enc []byte) (series.Points, error) {
+		decomp, err := b.decompress(c, enc)
+		if err != nil {
+			return nil, err
+		}
+		return c.undoBinary(decomp, l)
+	}
+}
+
+func (c *Compressor) binary(points series.Points, layout binaryLayout) (*bytes.Buffer, error) {
+	flat, err := c.flatten(points)
+	if err != nil {
+		return nil, err
+	}
+	var buf []byte
+	switch layout {
+	case varintLayout:
+		buf = appendVarints(flat)
+	case fixedLayout:
+		switch c.opts.Shuffle {
+		case ShuffleByte:
+			buf = shuffleBytes(flat)
+		case ShuffleBit:
+			buf = shuffleBits(flat)
+		default:
+			buf = appendFixed(flat)
+		}
+	}
+	return bytes.NewBuffer(buf), nil
+}
+
+func (c *Compressor) undoBinary(b []byte, layout binaryLayout) (series.Points, error) {
+	var flat []uint64
+	var err error
+	switch layout {
+	case varintLayout:
+		flat, err = readVarints(b)
+	case fixedLayout:
+		switch c.opts.Shuffle {
+		case ShuffleByte:
+			flat, err = unshuffleBytes(b)
+		case ShuffleBit:
+			flat, err = unshuffleBits(b)
+		default:
+			flat, err = readFixed(b)
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(flat)%2 != 0 {
+		return nil, fmt.Errorf("uneven number of values")
+	}
+	return c.unflatten(flat), nil
+}
+
+func appendVarints(flat []uint64) []byte {
+	buf := make([]byte, 0, 2*len(flat))
+	for _, v := range flat {
+		buf = binary.AppendUvarint(buf, v)
+	}
+	return buf
+}
+
+func readVarints(b []byte) ([]uint64, error) {
+	flat := make([]uint64, 0, len(b)/2)
+	for len(b) > 0 {
+		v, n := binary.Uvarint(b)
+		if n <= 0 {
+			return nil, fmt.Errorf("invalid varint")
+		}
+		flat = append(flat, v)
+		b = b[n:]
+	}
+	return flat, nil
+}
+
+func appendFixed(flat []uint64) []byte {
+	buf := make([]byte, 0, 8*len(flat))
+	for _, v := range flat {
+		buf = binary.LittleEndian.AppendUint64(buf, v)
+	}
+	return buf
+}
+
+func readFixed(b []byte) ([]uint64, error) {
+	if len(b)%8 != 0 {
+		return nil, fmt.Errorf("invalid fixed width length: %d", len(b))
+	}
+	flat := make([]uint64, len(b)/8)
+	for i := range flat {
+		flat[i] = binary.LittleEndian.Uint64(b[i*8:])
+	}
+	return flat, nil
+}
@@ -0,0 +1,74 @@
+package compress
+
+import (
+	"fmt"
+)
+
+// bitWriter packs values into a byte slice, most significant bit first.
+type bitWriter struct {
+	buf   []byte
+	count uint8 // number of free bits in the last byte
+}
+
+func (w *bitWriter) writeBit(bit bool) {
+	if bit {
+		w.writeBits(1, 1)
+	} else {
+		w.writeBits(0, 1)
+	}
+}
+
+// writeBits writes the nbits least significant bits of v.
+func (w *bitWriter) writeBits(v uint64, nbits int) {
+	for nbits > 0 {
+		if w.count == 0 {
+			w.buf = append(w.buf, 0)
+			w.count = 8
+		}
+		n := nbits
+		if n > int(w.count) {
+			n = int(w.count)
+		}
+		// Take the top n of the remaining nbits.
+		bits := byte((v >> (nbits - n)) & (1<<n - 1))
+		w.buf[len(w.buf)-1] |= bits << (w.count - uint8(n))
+		w.count -= uint8(n)
+		nbits -= n
+	}
+}
+
+func (w *bitWriter) bytes() []byte {
+	return w.buf
+}
+
+// bitReader reads values written by bitWriter.
+type bitReader struct {
+	buf []byte
+	pos int // position in bits
+}
+
+var errShortBitstream = fmt.Errorf("unexpected end of bitstream")
+
+func (r *bitReader) readBit() (bool, error) {
+	v, err := r.readBits(1)
+	return v == 1, err
+}
+
+func (r *bitReader) readBits(nbits int) (uint64, error) {
+	if r.pos+nbits > len(r.buf)*8 {
+		return 0, errShortBitstream
+	}
+	var v uint64
+	for nbits > 0 {
+		byteIdx, bitIdx := r.pos/8, r.pos%8
+		n := 8 - bitIdx
+		if n > nbits {
+			n = nbits
+		}
+		bits := (r.buf[byteIdx] >> (8 - bitIdx - n)) & (1<<n - 1)
+		v = v<<n | uint64(bits)
+		r.pos += n
+		nbits -= n
+	}
+	return v, nil
+}
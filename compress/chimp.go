@@ -0,0 +1,247 @@
+package compress
+
+import (
+	"fmt"
+	"math"
+	"math/bits"
+
+	"github.com/smpanaro/time-series-compression/series"
+)
+
+// Chimp and Chimp128 are XOR based float encoders from "Chimp: Efficient
+// Lossless Floating Point Compression for Time Series Databases" (Liakos et
+// al., 2022). Chimp128 XORs against whichever of the previous 128 values
+// shares the most trailing bits instead of only the previous one.
+
+// Leading zero counts are rounded down to one of 8 values so they can be
+// stored in 3 bits.
+var (
+	chimpLeadingRound = [65]uint8{
+		0, 0, 0, 0, 0, 0, 0, 0,
+		8, 8, 8, 8, 12, 12, 12, 12,
+		16, 16, 18, 18, 20, 20, 22, 22,
+		24, 24, 24, 24, 24, 24, 24, 24,
+		24, 24, 24, 24, 24, 24, 24, 24,
+		24, 24, 24, 24, 24, 24, 24, 24,
+		24, 24, 24, 24, 24, 24, 24, 24,
+		24, 24, 24, 24, 24, 24, 24, 24,
+		24,
+	}
+	chimpLeadingRepresentation = [25]uint64{0: 0, 8: 1, 12: 2, 16: 3, 18: 4, 20: 5, 22: 6, 24: 7}
+	chimpLeadingValue          = [8]uint8{0, 8, 12, 16, 18, 20, 22, 24}
+)
+
+const (
+	// chimpNoLeading forces the next value to write its leading zero count.
+	chimpNoLeading = 65
+
+	chimpThreshold = 6
+
+	chimp128Window    = 128
+	chimp128IndexBits = 7
+	chimp128Threshold = chimpThreshold + chimp128IndexBits
+	chimp128KeyBits   = chimp128Threshold + 1
+)
+
+type chimpEncoder struct {
+	w           *bitWriter
+	window      int // 1 for Chimp, chimp128Window for Chimp128
+	started     bool
+	storedLead  uint8
+	index       int
+	stored      []uint64
+	indexForKey []int
+}
+
+func newChimpEncoder(w *bitWriter, window int) *chimpEncoder {
+	e := &chimpEncoder{w: w, window: window, storedLead: chimpNoLeading, stored: make([]uint64, window)}
+	if window > 1 {
+		e.indexForKey = make([]int, 1<<chimp128KeyBits)
+	}
+	return e
+}
+
+func (e *chimpEncoder) threshold() int {
+	if e.window > 1 {
+		return chimp128Threshold
+	}
+	return chimpThreshold
+}
+
+// reference picks the previous value to XOR v against.
+func (e *chimpEncoder) reference(v uint64) int {
+	if e.window == 1 {
+		return 0
+	}
+	candidate := e.indexForKey[v&(1<<chimp128KeyBits-1)]
+	if e.index-candidate < e.window {
+		if bits.TrailingZeros64(v^e.stored[candidate%e.window]) > e.threshold() {
+			return candidate % e.window
+		}
+	}
+	return e.index % e.window
+}
+
+func (e *chimpEncoder) write(f float64) {
+	v := math.Float64bits(f)
+	if !e.started {
+		e.w.writeBits(v, 64)
+		e.stored[0] = v
+		e.started = true
+		if e.indexForKey != nil {
+			e.indexForKey[v&(1<<chimp128KeyBits-1)] = 0
+		}
+		return
+	}
+
+	ref := e.reference(v)
+	xor := v ^ e.stored[ref]
+	trailing := bits.TrailingZeros64(xor)
+
+	switch {
+	case xor == 0:
+		e.w.writeBits(0b00, 2)
+		e.writeIndex(ref)
+		e.storedLead = chimpNoLeading
+	case trailing > e.threshold():
+		lead := chimpLeadingRound[bits.LeadingZeros64(xor)]
+		significant := 64 - int(lead) - trailing
+		e.w.writeBits(0b01, 2)
+		e.writeIndex(ref)
+		e.w.writeBits(chimpLeadingRepresentation[lead], 3)
+		e.w.writeBits(uint64(significant), 6)
+		e.w.writeBits(xor>>trailing, significant)
+		e.storedLead = chimpNoLeading
+	default:
+		// ref is the previous value.
+		lead := chimpLeadingRound[bits.LeadingZeros64(xor)]
+		if lead == e.storedLead {
+			e.w.writeBits(0b10, 2)
+		} else {
+			e.w.writeBits(0b11, 2)
+			e.w.writeBits(chimpLeadingRepresentation[lead], 3)
+			e.storedLead = lead
+		}
+		e.w.writeBits(xor, 64-int(lead))
+	}
+
+	e.index++
+	e.stored[e.index%e.window] = v
+	if e.indexForKey != nil {
+		e.indexForKey[v&(1<<chimp128KeyBits-1)] = e.index
+	}
+}
+
+func (e *chimpEncoder) writeIndex(ref int) {
+	if e.window > 1 {
+		e.w.writeBits(uint64(ref), chimp128IndexBits)
+	}
+}
+
+type chimpDecoder struct {
+	r          *bitReader
+	window     int
+	started    bool
+	storedLead uint8
+	index      int
+	stored     []uint64
+}
+
+func newChimpDecoder(r *bitReader, window int) *chimpDecoder {
+	return &chimpDecoder{r: r, window: window, storedLead: chimpNoLeading, stored: make([]uint64, window)}
+}
+
+func (d *chimpDecoder) readIndex() (int, error) {
+	if d.window == 1 {
+		return 0, nil
+	}
+	ref, err := d.r.readBits(chimp128IndexBits)
+	return int(ref), err
+}
+
+func (d *chimpDecoder) read() (float64, error) {
+	if !d.started {
+		v, err := d.r.readBits(64)
+		if err != nil {
+			return 0, err
+		}
+		d.stored[0] = v
+		d.started = true
+		return math.Float64frombits(v), nil
+	}
+
+	flag, err := d.r.readBits(2)
+	if err != nil {
+		return 0, err
+	}
+
+	var v uint64
+	switch flag {
+	case 0b00:
+		ref, err := d.readIndex()
+		if err != nil {
+			return 0, err
+		}
+		v = d.stored[ref]
+		d.storedLead = chimpNoLeading
+	case 0b01:
+		ref, err := d.readIndex()
+		if err != nil {
+			return 0, err
+		}
+		lead, err := d.r.readBits(3)
+		if err != nil {
+			return 0, err
+		}
+		significant, err := d.r.readBits(6)
+		if err != nil {
+			return 0, err
+		}
+		xor, err := d.r.readBits(int(significant))
+		if err != nil {
+			return 0, err
+		}
+		trailing := 64 - int(chimpLeadingValue[lead]) - int(significant)
+		if trailing < 0 {
+			return 0, fmt.Errorf("invalid chimp significant bits: %d", significant)
+		}
+		v = d.stored[ref] ^ xor<<trailing
+		d.storedLead = chimpNoLeading
+	default:
+		if flag == 0b11 {
+			lead, err := d.r.readBits(3)
+			if err != nil {
+				return 0, err
+			}
+			d.storedLead = chimpLeadingValue[lead]
+		}
+		if d.storedLead == chimpNoLeading {
+			return 0, fmt.Errorf("chimp leading zeros not set")
+		}
+		xor, err := d.r.readBits(64 - int(d.storedLead))
+		if err != nil {
+			return 0, err
+		}
+		v = d.stored[d.index%d.window] ^ xor
+	}
+
+	d.index++
+	d.stored[d.index%d.window] = v
+	return math.Float64frombits(v), nil
+}
+
+func (c *Compressor) compressChimp(points series.Points) ([]byte, error) {
+	return c.compressXOR(points, func(w *bitWriter) floatEncoder { return newChimpEncoder(w, 1) })
+}
+
+func (c *Compressor) decompressChimp(b []byte) (series.Points, error) {
+	return c.decompressXOR(b, func(r *bitReader) floatDecoder { return newChimpDecoder(r, 1) })
+}
+
+func (c *Compressor) compressChimp128(points series.Points) ([]byte, error) {
+	return c.compressXOR(points, func(w *bitWriter) floatEncoder { return newChimpEncoder(w, chimp128Window) })
+}
+
+func (c *Compressor) decompressChimp128(b []byte) (series.Points, error) {
+	return c.decompressXOR(b, func(r *bitReader) floatDecoder { return newChimpDecoder(r, chimp128Window) })
+}
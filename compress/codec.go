@@ -0,0 +1,113 @@
+package compress
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/smpanaro/time-series-compression/series"
+)
+
+// Codec is a compression method that can be registered with Register.
+type Codec interface {
+	// Method is the name the codec is registered and selected by.
+	Method() Method
+	// Encode compresses points according to opts.
+	Encode(points series.Points, opts Options) ([]byte, error)
+	// Decode reverses Encode. opts must match the options used to encode b.
+	Decode(b []byte, opts Options) (series.Points, error)
+}
+
+var codecs = map[Method]Codec{}
+
+// Register makes a codec available to Compressor and adds its Method to
+// AllMethods. It is meant to be called from an init function and panics if
+// the codec is nil or its Method is already registered.
+func Register(codec Codec) {
+	if codec == nil {
+		panic("compress: Register codec is nil")
+	}
+	method := codec.Method()
+	if strings.Contains(string(method), PipelineSeparator) {
+		panic(fmt.Sprintf("compress: Register method %s contains %q", method, PipelineSeparator))
+	}
+	if _, dup := codecs[method]; dup {
+		panic(fmt.Sprintf("compress: Register called twice for method %s", method))
+	}
+	codecs[method] = codec
+	AllMethods = append(AllMethods, method)
+}
+
+// Lookup returns the codec registered for method. Methods containing
+// PipelineSeparator are parsed with ParsePipeline instead.
+func Lookup(method Method) (Codec, bool) {
+	codec, err := lookup(method)
+	return codec, err == nil
+}
+
+// lookup is Lookup with an error that explains why method is not supported.
+func lookup(method Method) (Codec, error) {
+	if codec, ok := codecs[method]; ok {
+		return codec, nil
+	}
+	if strings.Contains(string(method), PipelineSeparator) {
+		p, err := ParsePipeline(string(method))
+		if err != nil {
+			return nil, err
+		}
+		return p, nil
+	}
+	return nil, fmt.Errorf("unsupported algorithm: %s", method)
+}
+
+// compressorCodec adapts the Compressor's built-in methods to Codec.
+type compressorCodec struct {
+	method     Method
+	compress   func(*Compressor, series.Points) ([]byte, error)
+	decompress func(*Compressor, []byte) (series.Points, error)
+}
+
+func (c compressorCodec) Method() Method {
+	return c.method
+}
+
+func (c compressorCodec) Encode(points series.Points, opts Options) ([]byte, error) {
+	return c.compress(NewCompressorOptions(opts), points)
+}
+
+func (c compressorCodec) Decode(b []byte, opts Options) (series.Points, error) {
+	return c.decompress(NewCompressorOptions(opts), b)
+}
+
+func (c compressorCodec) Levels() (Levels, bool) {
+	levels, ok := builtinLevels[c.method]
+	return levels, ok
+}
+
+func init() {
+	for _, codec := range append([]compressorCodec{
+		{Simple8b, (*Compressor).compressSimple8b, (*Compressor).decompressSimple8b},
+		{Gorilla, (*Compressor).compressGorilla, (*Compressor).decompressGorilla},
+		{Chimp, (*Compressor).compressChimp, (*Compressor).decompressChimp},
+		{Chimp128, (*Compressor).compressChimp128, (*Compressor).decompressChimp128},
+		{ALP, (*Compressor).compressALP, (*Compressor).decompressALP},
+		{BP32, (*Compressor).compressBP32, (*Compressor).decompressBP32},
+		{FastPFOR, (*Compressor).compressFastPFOR, (*Compressor).decompressFastPFOR},
+		{PFORDelta, (*Compressor).compressPFORDelta, (*Compressor).decompressPFORDelta},
+		{CSV, (*Compressor).compressCSV, (*Compressor).decompressCSV},
+		{ZstdCSV, (*Compressor).compressZstdCSV, (*Compressor).decompressZstdCSV},
+		{ZstdDictCSV, (*Compressor).compressZstdDictCSV, (*Compressor).decompressZstdDictCSV},
+		{GzipCSV, (*Compressor).compressGzipCSV, (*Compressor).decompressGzipCSV},
+		{ZlibCSV, (*Compressor).compressZlibCSV, (*Compressor).decompressZlibCSV},
+		{BrotliCSV, (*Compressor).compressBrotliCSV, (*Compressor).decompressBrotliCSV},
+		{LzfseCSV, (*Compressor).compressLzfseCSV, (*Compressor).decompressLzfseCSV},
+		// CPATH=/opt/homebrew/include go run . evaluate -a lzma-csv -p fixtures/brew2.txt
+		{LzmaCSV, (*Compressor).compressLzmaCSV, (*Compressor).decompressLzmaCSV},
+		{Lz4CSV, (*Compressor).compressLz4CSV, (*Compressor).decompressLz4CSV},
+		{SnappyCSV, (*Compressor).compressSnappyCSV, (*Compressor).decompressSnappyCSV},
+	}, binaryCodecs()...) {
+		if codec.method == LzfseCSV && !lzfseAvailable {
+			continue
+		}
+		Register(codec)
+	}
+}
@@ -0,0 +1,78 @@
+package compress
+
+import (
+	"testing"
+
+	"github.com/smpanaro/time-series-compression/series"
+	"github.com/stretchr/testify/require"
+)
+
+// reverseCodec stores the CSV encoding backwards.
+type reverseCodec struct{}
+
+func (reverseCodec) Method() Method { return Method("reverse-csv") }
+
+func (reverseCodec) Encode(points series.Points, opts Options) ([]byte, error) {
+	csv, _ := Lookup(CSV)
+	enc, err := csv.Encode(points, opts)
+	if err != nil {
+		return nil, err
+	}
+	return reverse(enc), nil
+}
+
+func (reverseCodec) Decode(b []byte, opts Options) (series.Points, error) {
+	csv, _ := Lookup(CSV)
+	return csv.Decode(reverse(b), opts)
+}
+
+func reverse(b []byte) []byte {
+	r := make([]byte, len(b))
+	for i := range b {
+		r[len(b)-1-i] = b[i]
+	}
+	return r
+}
+
+// unregister reverses Register so tests do not leak codecs into the global
+// registry.
+func unregister(method Method) {
+	delete(codecs, method)
+	for i, m := range AllMethods {
+		if m == method {
+			AllMethods = append(AllMethods[:i:i], AllMethods[i+1:]...)
+			break
+		}
+	}
+}
+
+func TestRegister(t *testing.T) {
+	Register(reverseCodec{})
+	t.Cleanup(func() { unregister(Method("reverse-csv")) })
+	require.True(t, AllMethods.Contains(Method("reverse-csv")))
+	require.Panics(t, func() { Register(reverseCodec{}) })
+
+	points, err := series.FromFile("../fixtures/brew2.txt")
+	require.NoError(t, err)
+
+	c := NewCompressor(Method("reverse-csv"))
+	enc, err := c.Compress(points)
+	require.NoError(t, err)
+
+	dec, err := c.Decompress(enc)
+	require.NoError(t, err)
+	require.True(t, points.MilliEqual(dec))
+}
+
+func TestAllMethods_Build(t *testing.T) {
+	// lzfse is only registered when it can be built.
+	require.Equal(t, lzfseAvailable, AllMethods.Contains(LzfseCSV))
+	for _, method := range []Method{ZstdCSV, BrotliCSV, LzmaCSV} {
+		require.True(t, AllMethods.Contains(method), method)
+	}
+}
+
+func TestCompressor_UnknownMethod(t *testing.T) {
+	_, err := NewCompressor(Method("unknown")).Compress(nil)
+	require.Error(t, err)
+}
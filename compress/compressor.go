@@ -1,10 +1,5 @@
 package compress
 
-/*
-#cgo LDFLAGS: -L/opt/homebrew/lib -lbrotlicommon
-*/
-import "C"
-
 import (
 	"bytes"
 	"compress/gzip"
@@ -12,17 +7,14 @@ import (
 	"encoding/binary"
 	"fmt"
 	"io"
-	"time"
 
-	"github.com/DataDog/zstd"
-	"github.com/andybalholm/brotli"
-	"github.com/blacktop/lzfse-cgo"
-	"github.com/danielrh/go-xz"
 	"github.com/dataence/encoding/bp32"
+	"github.com/dataence/encoding/composition"
 	"github.com/dataence/encoding/cursor"
-	"github.com/google/brotli/go/cbrotli"
+	"github.com/dataence/encoding/variablebyte"
 	"github.com/jwilder/encoding/simple8b"
-	"github.com/keisku/gorilla"
+	"github.com/klauspost/compress/snappy"
+	"github.com/pierrec/lz4/v4"
 	"github.com/smpanaro/time-series-compression/series"
 )
 
@@ -31,9 +23,95 @@ var errUnimplemented = fmt.Errorf("unimplemented")
 type Options struct {
 	Method     Method
 	Interleave bool
+	// TimestampBuckets are the bit widths of the delta-of-delta buckets used
+	// by Gorilla and Chimp timestamps, smallest first. Larger values are
+	// written in 64 bits. Defaults to DefaultTimestampBuckets.
+	TimestampBuckets []int
+	// Verify decodes the compressed output and checks it against the input.
+	// The zero value is VerifyOn, so Compress always checks its output unless
+	// this is VerifyOff.
+	Verify VerifyMode
+	// Precision is the number of integer steps per unit that values are
+	// stored with by methods that convert them to integers, e.g. 1000 for
+	// milligrams from grams or 1 for whole units. It is recorded in the
+	// container header. Defaults to series.MilliPrecision.
+	Precision int64
+	// Resolution is the unit timestamps are stored and delta encoded in.
+	// Anything finer is truncated. It is recorded in the container header.
+	// Defaults to series.Millisecond.
+	Resolution series.Resolution
+	// TimeDeltaOrder and ValueDeltaOrder are how many times timestamps and
+	// values are differenced before integer methods (simple-8b, BP32, the
+	// PFOR methods and the CSV methods) encode them. 2 is delta-of-delta,
+	// which turns near regular timestamps into mostly zeros. They are
+	// recorded in the container header. Both default to 1 and can be at most
+	// MaxDeltaOrder.
+	TimeDeltaOrder  int
+	ValueDeltaOrder int
+	// Level is the compression level of methods that have them (see
+	// MethodLevels). nil uses the method's default.
+	Level *int
+	// Dictionary is the zstd dictionary used by ZstdDictCSV. Its ID is stored
+	// in the payload, and the same dictionary must be given to decompress it.
+	// nil compresses without a dictionary.
+	Dictionary *Dictionary
+	// Shuffle groups the bytes or bits of the fixed-width integers written
+	// by the *-fixed methods before they are compressed. It is recorded in
+	// the container header.
+	Shuffle Shuffle
+}
+
+// MaxDeltaOrder is the largest supported TimeDeltaOrder and ValueDeltaOrder.
+const MaxDeltaOrder = 4
+
+func (o Options) precision() int64 {
+	if o.Precision == 0 {
+		return series.MilliPrecision
+	}
+	return o.Precision
+}
+
+func (o Options) resolution() series.Resolution {
+	if o.Resolution == 0 {
+		return series.Millisecond
+	}
+	return o.Resolution
+}
+
+func (o Options) timeDeltaOrder() int {
+	if o.TimeDeltaOrder == 0 {
+		return 1
+	}
+	return o.TimeDeltaOrder
+}
+
+func (o Options) valueDeltaOrder() int {
+	if o.ValueDeltaOrder == 0 {
+		return 1
+	}
+	return o.ValueDeltaOrder
+}
+
+// deltaEncoded rounds points to the resolution and precision and then
+// differences timestamps and values.
+func (o Options) deltaEncoded(points series.Points) series.Points {
+	resolution := o.resolution()
+	return points.Rounded(resolution, o.precision()).
+		DeltaEncodedOrder(o.timeDeltaOrder(), true, false, resolution).
+		DeltaEncodedOrder(o.valueDeltaOrder(), false, true, resolution)
+}
+
+// deltaDecoded reverses deltaEncoded. Values are rounded again to remove
+// floating point error accumulated by the running sums.
+func (o Options) deltaDecoded(points series.Points) series.Points {
+	resolution := o.resolution()
+	return points.DeltaDecodedOrder(o.valueDeltaOrder(), false, true, resolution).
+		DeltaDecodedOrder(o.timeDeltaOrder(), true, false, resolution).
+		Rounded(resolution, o.precision())
 }
 
 type Compressor struct {
+	opts       Options
 	algorithm  Method
 	interleave bool // interleave time and value when necessary
 	csvEncoder CSVPointEncoder
@@ -44,169 +122,324 @@ func NewCompressor(algorithm Method) *Compressor {
 }
 
 func NewCompressorOptions(opts Options) *Compressor {
-	return &Compressor{algorithm: opts.Method, interleave: opts.Interleave}
+	return &Compressor{
+		opts:       opts,
+		algorithm:  opts.Method,
+		interleave: opts.Interleave,
+		csvEncoder: CSVPointEncoder{
+			Resolution:      opts.Resolution,
+			Precision:       opts.Precision,
+			TimeDeltaOrder:  opts.TimeDeltaOrder,
+			ValueDeltaOrder: opts.ValueDeltaOrder,
+		},
+	}
+}
+
+// Options returns the options the Compressor was created with.
+func (c *Compressor) Options() Options {
+	return c.opts
 }
 
+// Compress encodes points with the Codec registered for the configured Method
+// and wraps the result in a container (see Header). It returns ErrNoPoints if
+// points is empty.
 func (c *Compressor) Compress(points series.Points) ([]byte, error) {
-	switch c.algorithm {
-	case Simple8b:
-		return c.compressSimple8b(points)
-	case Gorilla:
-		return c.compressGorilla(points)
-	case BP32:
-		return c.compressBP32(points)
-	case CSV:
-		return c.compressCSV(points)
-	case ZstdCSV:
-		return c.compressZstdCSV(points)
-	case GzipCSV:
-		return c.compressGzipCSV(points)
-	case ZlibCSV:
-		return c.compressZlibCSV(points)
-	case BrotliCSV:
-		return c.compressBrotliCSV(points)
-	case LzfseCSV:
-		return c.compressLzfseCSV(points)
-	case LzmaCSV:
-		// CPATH=/opt/homebrew/include go run . evaluate -a lzma-csv -p fixtures/brew2.txt
-		return c.compressLzmaCSV(points)
-	default:
-		return nil, fmt.Errorf("unsupported algorithm: %s", c.algorithm)
+	codec, err := lookup(c.algorithm)
+	if err != nil {
+		return nil, err
+	}
+	if len(points) == 0 {
+		return nil, ErrNoPoints
+	}
+	if c.opts.Precision < 0 {
+		return nil, fmt.Errorf("invalid precision: %d", c.opts.Precision)
+	}
+	if _, ok := resolutionFlags[c.opts.resolution()]; !ok {
+		return nil, fmt.Errorf("unsupported resolution: %s", c.opts.Resolution)
+	}
+	for _, order := range []int{c.opts.TimeDeltaOrder, c.opts.ValueDeltaOrder} {
+		if order < 0 || order > MaxDeltaOrder {
+			return nil, fmt.Errorf("invalid delta order: %d. must be between 1 and %d", order, MaxDeltaOrder)
+		}
+	}
+	if err := validateLevel(c.opts); err != nil {
+		return nil, err
+	}
+	if err := validateShuffle(c.opts); err != nil {
+		return nil, err
+	}
+	payload, err := codec.Encode(points, c.opts)
+	if err != nil {
+		return nil, err
 	}
+	if err := c.verify(codec, points, payload); err != nil {
+		return nil, err
+	}
+	b := newHeader(c.opts, points, payload).append(make([]byte, 0, 64+len(payload)))
+	return append(b, payload...), nil
 }
 
-func (c *Compressor) compressSimple8b(points series.Points) ([]byte, error) {
-	encoder := simple8b.NewEncoder()
-
-	for _, v := range points.DeltaEncoded(true, true).Flatten(c.interleave) {
-		if err := encoder.Write(v); err != nil {
+// Decompress reverses Compress. Containers are decoded using the options in
+// their header and the Compressor's Dictionary. Anything else is treated as a
+// bare Codec payload and decoded with the Compressor's own Options.
+func (c *Compressor) Decompress(b []byte) (series.Points, error) {
+	if IsContainer(b) {
+		h, payload, err := ReadHeader(b)
+		if err != nil {
 			return nil, err
 		}
+		return decodePayload(h, payload, c.opts.Dictionary)
 	}
-
-	enc, err := encoder.Bytes()
+	codec, err := lookup(c.algorithm)
 	if err != nil {
 		return nil, err
 	}
+	return codec.Decode(b, c.opts)
+}
 
-	// Decode to verify data is recoverable.
-	err = func(enc []byte, target series.Points) error {
-		decoder := simple8b.NewDecoder(enc)
-		decoded := make([]uint64, 0, len(points)*2)
-		for decoder.Next() { // Calling Read() before Next() returns 0.
-			decoded = append(decoded, decoder.Read())
+// flatten delta encodes points and flattens them into integers according to
+// the Compressor's layout, resolution and precision. First order time deltas
+// are not zigzag encoded (see zigZagTimes), so it returns an error if any are
+// negative.
+func (c *Compressor) flatten(points series.Points) ([]uint64, error) {
+	flat := c.opts.deltaEncoded(points).Flatten(c.interleave, c.opts.resolution(), c.opts.precision())
+	if c.opts.timeDeltaOrder() == 1 {
+		for i := range points {
+			t := i
+			if c.interleave {
+				t = 2 * i
+			}
+			if int64(flat[t]) < 0 {
+				return nil, fmt.Errorf("timestamp of point %d is before the previous point or 1970. sort the points or use a time delta order of 2", i)
+			}
 		}
-		decPoints := series.FromFlat(decoded, c.interleave)
-		decPoints = decPoints.DeltaDecoded(true, true)
-		if !target.MilliEqual(decPoints) {
-			// return fmt.Errorf("decoded points do not match original points")
-		}
-		return nil
-	}(enc, points)
+	}
+	c.zigZagTimes(flat, func(v uint64) uint64 { return series.ZigZagEncode64(int64(v)) })
+	return flat, nil
+}
 
-	return enc, err
+// unflatten reverses flatten.
+func (c *Compressor) unflatten(flat []uint64) series.Points {
+	c.zigZagTimes(flat, func(v uint64) uint64 { return uint64(series.ZigZagDecode64(v)) })
+	return c.opts.deltaDecoded(series.FromFlat(flat, c.interleave, c.opts.resolution(), c.opts.precision()))
 }
 
-func (c *Compressor) compressGorilla(points series.Points) ([]byte, error) {
-	if len(points) == 0 {
-		return nil, nil
+// zigZagTimes applies fn to the timestamps in flat when they can be negative.
+// First order deltas of sorted timestamps never are, so they are left alone
+// to save a bit per timestamp.
+func (c *Compressor) zigZagTimes(flat []uint64, fn func(uint64) uint64) {
+	if c.opts.timeDeltaOrder() == 1 {
+		return
 	}
+	c.mapTimes(flat, fn)
+}
 
-	buf := new(bytes.Buffer)
-	first := points[0]
-	header := uint32(first.Time.Unix())
-
-	gc, finish, err := gorilla.NewCompressor(buf, header)
-	if err != nil {
-		return nil, err
+// splitFirstSample removes the first timestamp and value from flat.
+func (c *Compressor) splitFirstSample(flat []uint64) (time, value uint64, rest []uint64) {
+	v := len(flat) / 2
+	if c.interleave {
+		v = 1
 	}
+	rest = make([]uint64, 0, len(flat)-2)
+	rest = append(rest, flat[1:v]...)
+	rest = append(rest, flat[v+1:]...)
+	return flat[0], flat[v], rest
+}
 
-	// We lose the milliseconds when truncating the header.
-	// Add a synthetic point at the start so we can re-add them.
-	// (The first point will be the int truncated timestamp,
-	// all other points will be include the milliOffset.)
-	milliOffset := first.TimeMilli() - (first.Time.Unix() * 1000)
-	if err := gc.Compress(0, 0); err != nil { // Values don't matter, this is dropped.
-		return nil, err
+// joinFirstSample reverses splitFirstSample.
+func (c *Compressor) joinFirstSample(time, value uint64, rest []uint64) []uint64 {
+	v := len(rest)/2 + 1
+	if c.interleave {
+		v = 1
 	}
+	flat := make([]uint64, 0, len(rest)+2)
+	flat = append(flat, time)
+	flat = append(flat, rest[:v-1]...)
+	flat = append(flat, value)
+	return append(flat, rest[v-1:]...)
+}
 
-	for _, pt := range points {
-		// The library recommends using second precision, but we want millisecond.
-		// Millisecond timestamps won't fit in 32 bits, so shift them.
-		// 2^31 milliseconds is 20+ days.
-		timeDelta := pt.TimeMilli() + milliOffset - first.TimeMilli()
-		if err := gc.Compress(uint32(timeDelta), float64(pt.ValueMilli())); err != nil {
-			return nil, err
+// mapTimes applies fn to the timestamps in flat.
+func (c *Compressor) mapTimes(flat []uint64, fn func(uint64) uint64) {
+	for i := range flat {
+		isTime := i < len(flat)/2
+		if c.interleave {
+			isTime = i%2 == 0
+		}
+		if isTime {
+			flat[i] = fn(flat[i])
 		}
 	}
+}
 
-	if err := finish(); err != nil {
+func (c *Compressor) compressSimple8b(points series.Points) ([]byte, error) {
+	flat, err := c.flatten(points)
+	if err != nil {
 		return nil, err
 	}
-	compressed := buf.Bytes()
-
-	// Decode to verify data is recoverable.
-	err = func(enc []byte, target series.Points) error {
-		gd, header, err := gorilla.NewDecompressor(bytes.NewBuffer(enc))
-		if err != nil {
-			return err
-		}
-
-		baseTime := time.Unix(int64(header), 0)
+	if len(flat) > 0 && flat[0] > simple8b.MaxValue {
+		return nil, fmt.Errorf("the first timestamp does not fit in simple-8b's 60 bits at %s resolution. use a coarser resolution", c.opts.resolution())
+	}
+	return encodeSimple8b(flat)
+}
 
-		decompressed := make(series.Points, 0, len(target))
-		it := gd.Iterator()
+func (c *Compressor) decompressSimple8b(b []byte) (series.Points, error) {
+	flat, err := decodeSimple8b(b)
+	if err != nil {
+		return nil, err
+	}
+	return c.unflatten(flat), nil
+}
 
-		// Drop the first dummy point.
-		if !it.Next() {
-			return fmt.Errorf("no points to decode")
-		}
+func encodeSimple8b(flat []uint64) ([]byte, error) {
+	encoder := simple8b.NewEncoder()
 
-		for it.Next() {
-			timeDelta, milliValue := it.At()
-			t := baseTime.Add(time.Duration(timeDelta) * time.Millisecond)
-			v := float32(milliValue / 1000)
-			decompressed = append(decompressed, &series.Point{Time: t, Value: v})
+	for i, v := range flat {
+		// Check here since the encoder's error includes every buffered value.
+		if v > simple8b.MaxValue {
+			return nil, fmt.Errorf("integer %d is %d, above simple-8b's limit of 2^60-1", i, v)
 		}
-
-		if err := it.Err(); err != nil {
-			return err // decompression error
+		if err := encoder.Write(v); err != nil {
+			return nil, err
 		}
+	}
 
-		if !target.MilliEqual(decompressed) {
-			// return fmt.Errorf("decoded points do not match original points")
-		}
+	return encoder.Bytes()
+}
 
-		return nil
-	}(compressed, points)
+func decodeSimple8b(b []byte) ([]uint64, error) {
+	decoder := simple8b.NewDecoder(b)
+	decoded := make([]uint64, 0, len(b)/4)
+	for decoder.Next() { // Calling Read() before Next() returns 0.
+		decoded = append(decoded, decoder.Read())
+	}
+	if len(decoded)%2 != 0 {
+		return nil, fmt.Errorf("uneven number of values")
+	}
+	if len(decoded) == 0 {
+		return nil, fmt.Errorf("no data")
+	}
 
-	return compressed, err
+	return decoded, nil
 }
 
+// compressBP32 writes the first sample's timestamp and value as uvarints,
+// then the outliers among the remaining integers (see splitOutliers) and the
+// BP32 words packing the rest. The first timestamp is absolute and does not
+// fit in BP32's 32 bits, so it is kept out of the blocks along with the first
+// value.
 func (c *Compressor) compressBP32(points series.Points) ([]byte, error) {
-	input := make([]int32, 0, len(points)*2)
-	for _, v := range points.DeltaEncoded(true, true).Flatten(c.interleave) {
-		input = append(input, int32(v))
+	flat, err := c.flatten(points)
+	if err != nil {
+		return nil, err
+	}
+	if len(flat) == 0 {
+		return nil, fmt.Errorf("no data")
+	}
+	firstTime, firstValue, rest := c.splitFirstSample(flat)
+	buf := binary.AppendUvarint(nil, firstTime)
+	buf = binary.AppendUvarint(buf, firstValue)
+	input, outliers := splitOutliers(rest)
+	buf = append(buf, outliers...)
+	if len(input) == 0 {
+		return buf, nil
 	}
 
-	// Not totally sure I'm calling this correctly.
-	encoder := bp32.New()
+	// BP32 only packs whole blocks of 128, so let variable byte
+	// encoding pick up the remainder.
+	encoder := composition.New(bp32.New(), variablebyte.New())
 	inpos := cursor.New()
 	outpos := cursor.New()
-	output := make([]int32, 2*len(input))
+	output := make([]int32, bp32MaxWords(len(input)))
 	if err := encoder.Compress(input, inpos, len(input), output, outpos); err != nil {
 		return nil, err
 	}
+	return appendWords(buf, output[:outpos.Get()]), nil
+}
 
-	// Re-pack output into a byte array.
-	buf := make([]byte, 0, 4*len(output))
-	for i := 0; i < outpos.Get(); i++ {
-		buf = binary.LittleEndian.AppendUint32(buf, uint32(output[i]))
+// bp32MaxWords is the most words BP32 and variable byte encoding write for n
+// integers: the number of bit packed values, then for each block of 128 a
+// word of bit widths and at most 32 bits per value, then at most 5 bytes per
+// remaining value, padded to a word.
+func bp32MaxWords(n int) int {
+	blocks, remainder := n/128, n%128
+	return 1 + blocks*(1+128) + (5*remainder+3)/4
+}
+
+func (c *Compressor) decompressBP32(b []byte) (series.Points, error) {
+	firstTime, n := binary.Uvarint(b)
+	if n <= 0 {
+		return nil, fmt.Errorf("invalid bp32 first timestamp")
+	}
+	b = b[n:]
+	firstValue, n := binary.Uvarint(b)
+	if n <= 0 {
+		return nil, fmt.Errorf("invalid bp32 first value")
+	}
+	b = b[n:]
+	outliers, b, err := readOutliers(b)
+	if err != nil {
+		return nil, err
 	}
 
-	// This library also has an implementation of fastpfor which compresses
-	// quite well, on par with the general purpose compression algorithms.
-	return buf, nil
+	var output []int32
+	if len(b) > 0 {
+		input, err := readWords(b)
+		if err != nil {
+			return nil, fmt.Errorf("invalid bp32 length: %w", err)
+		}
+		if err := checkBP32Blocks(input); err != nil {
+			return nil, err
+		}
+
+		// The first word is the number of bit packed values. Every
+		// variable byte encoded value takes at least one byte.
+		decoder := composition.New(bp32.New(), variablebyte.New())
+		inpos := cursor.New()
+		outpos := cursor.New()
+		output = make([]int32, int(uint32(input[0]))+len(b))
+		if err := decoder.Uncompress(input, inpos, len(input), output, outpos); err != nil {
+			return nil, err
+		}
+		output = output[:outpos.Get()]
+	}
+
+	rest, err := joinOutliers(output, outliers)
+	if err != nil {
+		return nil, err
+	}
+	if len(rest)%2 != 0 {
+		return nil, fmt.Errorf("uneven number of values")
+	}
+	return c.unflatten(c.joinFirstSample(firstTime, firstValue, rest)), nil
+}
+
+// checkBP32Blocks checks that the bit packed blocks at the start of input
+// have valid bit widths and fit in it, since the library trusts them.
+func checkBP32Blocks(input []int32) error {
+	packed := int(uint32(input[0]))
+	if packed%128 != 0 || packed/128 > len(input) {
+		return fmt.Errorf("invalid bp32 block length: %d", packed)
+	}
+	pos := 1
+	for block := 0; block < packed/128; block++ {
+		if pos >= len(input) {
+			return fmt.Errorf("bp32 block %d is truncated", block)
+		}
+		widths := uint32(input[pos])
+		pos++
+		for shift := 24; shift >= 0; shift -= 8 {
+			width := int(widths >> shift & 0xff)
+			if width > 32 {
+				return fmt.Errorf("invalid bp32 bit width: %d", width)
+			}
+			pos += width
+		}
+		if pos > len(input) {
+			return fmt.Errorf("bp32 block %d is truncated", block)
+		}
+	}
+	return nil
 }
 
 // compressCSV is barely a compression method. We just create a CSV but
@@ -216,50 +449,28 @@ func (c *Compressor) compressCSV(points series.Points) ([]byte, error) {
 
 	// os.WriteFile("fixtures/delta-brew3.csv", enc, 0644)
 
-	// Decode to verify data is recoverable.
-	err := func(enc []byte, target series.Points) error {
-		decoded, err := c.undoCSV(enc)
-		if err != nil {
-			return err
-		}
-		if !points.MilliEqual(decoded) {
-			return fmt.Errorf("decoded points do not match original points")
-		}
-		return nil
-	}(enc, points)
+	return enc, nil
+}
 
-	return enc, err
+func (c *Compressor) decompressCSV(b []byte) (series.Points, error) {
+	return c.undoCSV(b)
 }
 
-// Decode to verify data is recoverable.
 func (c *Compressor) compressGzipCSV(points series.Points) ([]byte, error) {
-	enc, err := c.compressGzip(c.csv(points))
+	return c.compressGzip(c.csv(points))
+}
+
+func (c *Compressor) decompressGzipCSV(b []byte) (series.Points, error) {
+	decomp, err := c.decompressGzip(b)
 	if err != nil {
 		return nil, err
 	}
-
-	err = func(enc []byte, target series.Points) error {
-		decomp, err := c.decompressGzip(enc)
-		if err != nil {
-			fmt.Println("decompress error")
-			return err
-		}
-		decoded, err := c.undoCSV(decomp)
-		if err != nil {
-			return err
-		}
-		if !points.MilliEqual(decoded) {
-			return fmt.Errorf("decoded points do not match original points")
-		}
-		return nil
-	}(enc, points)
-
-	return enc, err
+	return c.undoCSV(decomp)
 }
 
 func (c *Compressor) compressGzip(b *bytes.Buffer) ([]byte, error) {
 	var buf bytes.Buffer
-	w, err := gzip.NewWriterLevel(&buf, 5)
+	w, err := gzip.NewWriterLevel(&buf, c.level(flateLevels))
 	if err != nil {
 		return nil, err
 	}
@@ -284,32 +495,20 @@ func (c *Compressor) decompressGzip(b []byte) ([]byte, error) {
 }
 
 func (c *Compressor) compressZlibCSV(points series.Points) ([]byte, error) {
-	enc, err := c.compressZlib(c.csv(points))
+	return c.compressZlib(c.csv(points))
+}
+
+func (c *Compressor) decompressZlibCSV(b []byte) (series.Points, error) {
+	decomp, err := c.decompressZlib(b)
 	if err != nil {
 		return nil, err
 	}
-
-	err = func(enc []byte, target series.Points) error {
-		decomp, err := c.decompressZlib(enc)
-		if err != nil {
-			return err
-		}
-		decoded, err := c.undoCSV(decomp)
-		if err != nil {
-			return err
-		}
-		if !points.MilliEqual(decoded) {
-			return fmt.Errorf("decoded points do not match original points")
-		}
-		return nil
-	}(enc, points)
-
-	return enc, err
+	return c.undoCSV(decomp)
 }
 
 func (c *Compressor) compressZlib(b *bytes.Buffer) ([]byte, error) {
 	var buf bytes.Buffer
-	w, err := zlib.NewWriterLevel(&buf, 5)
+	w, err := zlib.NewWriterLevel(&buf, c.level(flateLevels))
 	if err != nil {
 		return nil, err
 	}
@@ -334,173 +533,98 @@ func (c *Compressor) decompressZlib(b []byte) ([]byte, error) {
 }
 
 func (c *Compressor) compressZstdCSV(points series.Points) ([]byte, error) {
-	enc, err := c.compressZstd(c.csv(points))
+	return c.compressZstd(c.csv(points))
+}
+
+func (c *Compressor) decompressZstdCSV(b []byte) (series.Points, error) {
+	decomp, err := c.decompressZstd(b)
 	if err != nil {
 		return nil, err
 	}
-
-	fmt.Printf("compress size: %v\n", len(enc))
-	// Decode to verify data is recoverable.
-	err = func(enc []byte, target series.Points) error {
-		decomp, err := c.decompressZstd(enc)
-		if err != nil {
-			return err
-		}
-		decoded, err := c.undoCSV(decomp)
-		if err != nil {
-			return err
-		}
-		if !points.MilliEqual(decoded) {
-			return fmt.Errorf("decoded points do not match original points")
-		}
-		return nil
-	}(enc, points)
-
-	return enc, err
+	return c.undoCSV(decomp)
 }
 
-func (c *Compressor) compressZstd(b *bytes.Buffer) ([]byte, error) {
-	// Use this since it wraps the official implementation (vs. a native go implementation).
-	fmt.Printf("input size: %v\n", b.Len())
-	return zstd.CompressLevel(nil, b.Bytes(), 22)
-}
-
-func (c *Compressor) decompressZstd(b []byte) ([]byte, error) {
-	return zstd.Decompress(nil, b)
+func (c *Compressor) compressBrotliCSV(points series.Points) ([]byte, error) {
+	return c.compressBrotli(c.csv(points))
 }
 
-func (c *Compressor) compressBrotliCSV(points series.Points) ([]byte, error) {
-	enc, err := c.compressBrotli(c.csv(points))
+func (c *Compressor) decompressBrotliCSV(b []byte) (series.Points, error) {
+	decomp, err := c.decompressBrotli(b)
 	if err != nil {
 		return nil, err
 	}
+	return c.undoCSV(decomp)
+}
 
-	// Decode to verify data is recoverable.
-	err = func(enc []byte, target series.Points) error {
-		decomp, err := c.decompressBrotli(enc)
-		if err != nil {
-			return err
-		}
-		decoded, err := c.undoCSV(decomp)
-		if err != nil {
-			return err
-		}
-		if !points.MilliEqual(decoded) {
-			return fmt.Errorf("decoded points do not match original points")
-		}
-		return nil
-	}(enc, points)
-	return enc, err
+func (c *Compressor) compressLzfseCSV(points series.Points) ([]byte, error) {
+	return c.compressLzfse(c.csv(points))
 }
 
-// CPATH=/opt/homebrew/include CGO_LDFLAGS="-L/opt/homebrew/lib -lbrotlicommon" go run . evaluate -a brotli-csv -p fixtures/brew1.txt
-func (c *Compressor) compressBrotli(b *bytes.Buffer) ([]byte, error) {
-	return cbrotli.Encode(b.Bytes(), cbrotli.WriterOptions{
-		Quality: 10,
-	})
+func (c *Compressor) decompressLzfseCSV(b []byte) (series.Points, error) {
+	return c.undoCSV(c.decompressLzfse(b))
 }
 
-func (c *Compressor) decompressBrotli(b []byte) ([]byte, error) {
-	return cbrotli.Decode(b)
+func (c *Compressor) compressLzmaCSV(points series.Points) ([]byte, error) {
+	return c.compressLzma(c.csv(points))
 }
 
-func (c *Compressor) compressBrotli1_5(b *bytes.Buffer) ([]byte, error) {
-	var buf bytes.Buffer
-	w := brotli.NewWriterLevel(&buf, 11)
-	_, err := w.Write(b.Bytes())
+func (c *Compressor) decompressLzmaCSV(b []byte) (series.Points, error) {
+	decomp, err := c.decompressLzma(b)
 	if err != nil {
 		return nil, err
 	}
-	if err := w.Flush(); err != nil {
-		return nil, err
-	}
-	return buf.Bytes(), nil
+	return c.undoCSV(decomp)
 }
 
-func (c *Compressor) decompressBrotli1_5(b []byte) ([]byte, error) {
-	r := brotli.NewReader(bytes.NewBuffer(b))
-	return io.ReadAll(r)
+func (c *Compressor) compressLz4CSV(points series.Points) ([]byte, error) {
+	return c.compressLz4(c.csv(points))
 }
 
-func (c *Compressor) compressLzfseCSV(points series.Points) ([]byte, error) {
-	enc, err := c.compressLzfse(c.csv(points))
+func (c *Compressor) decompressLz4CSV(b []byte) (series.Points, error) {
+	decomp, err := c.decompressLz4(b)
 	if err != nil {
 		return nil, err
 	}
-
-	// Decode to verify data is recoverable.
-	err = func(enc []byte, target series.Points) error {
-		decomp := c.decompressLzfse(enc)
-		decoded, err := c.undoCSV(decomp)
-		if err != nil {
-			return err
-		}
-		if !points.MilliEqual(decoded) {
-			// return fmt.Errorf("decoded points do not match original points")
-		}
-		return nil
-	}(enc, points)
-	return enc, err
+	return c.undoCSV(decomp)
 }
 
-func (c *Compressor) compressLzfse(b *bytes.Buffer) ([]byte, error) {
-	encLen := b.Len() * 2
-	enc := make([]byte, encLen)
-	written := lzfse.EncodeBuffer(enc, uint(encLen), b.String(), uint(b.Len()), nil)
-	if written == 0 {
-		return nil, fmt.Errorf("compression failed")
+// compressLz4 writes an lz4 frame at the fastest compression level.
+func (c *Compressor) compressLz4(b *bytes.Buffer) ([]byte, error) {
+	var buf bytes.Buffer
+	w := lz4.NewWriter(&buf)
+	if _, err := w.Write(b.Bytes()); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
 	}
-	return enc[:written], nil
+	return buf.Bytes(), nil
 }
 
-func (c *Compressor) decompressLzfse(b []byte) []byte {
-	return lzfse.DecodeBuffer(b)
+func (c *Compressor) decompressLz4(b []byte) ([]byte, error) {
+	return io.ReadAll(lz4.NewReader(bytes.NewReader(b)))
 }
 
-func (c *Compressor) compressLzmaCSV(points series.Points) ([]byte, error) {
-	enc, err := c.compressLzma(c.csv(points))
-	if err != nil {
-		return nil, err
-	}
-	err = func(enc []byte, target series.Points) error {
-		decomp, err := c.decompressLzma(enc)
-		if err != nil {
-			return err
-		}
-		decoded, err := c.undoCSV(decomp)
-		if err != nil {
-			return err
-		}
-		if !points.MilliEqual(decoded) {
-			return fmt.Errorf("decoded points do not match original points")
-		}
-		return nil
-	}(enc, points)
-	return enc, err
+func (c *Compressor) compressSnappyCSV(points series.Points) ([]byte, error) {
+	return c.compressSnappy(c.csv(points))
 }
 
-func (c *Compressor) compressLzma(b *bytes.Buffer) ([]byte, error) {
-	comp := bytes.NewBuffer(nil)
-	w := xz.NewCompressionWriterPreset(comp, 1)
-	_, err := w.Write(b.Bytes())
+func (c *Compressor) decompressSnappyCSV(b []byte) (series.Points, error) {
+	decomp, err := c.decompressSnappy(b)
 	if err != nil {
 		return nil, err
 	}
+	return c.undoCSV(decomp)
+}
 
-	if err := w.Close(); err != nil {
-		return nil, err
-	}
-	return comp.Bytes(), nil
+// compressSnappy writes a snappy block, which has less overhead than the
+// framed stream format.
+func (c *Compressor) compressSnappy(b *bytes.Buffer) ([]byte, error) {
+	return snappy.Encode(nil, b.Bytes()), nil
 }
 
-func (c *Compressor) decompressLzma(b []byte) ([]byte, error) {
-	r := xz.NewDecompressionReader(bytes.NewBuffer(b))
-	decomp := make([]byte, len(b)*20)
-	nread, err := r.Read(decomp)
-	if err != nil {
-		return nil, err
-	}
-	return decomp[:nread], nil
+func (c *Compressor) decompressSnappy(b []byte) ([]byte, error) {
+	return snappy.Decode(nil, b)
 }
 
 func (c *Compressor) csv(points series.Points) *bytes.Buffer {
@@ -1,18 +1,203 @@
 package compress
 
 import (
+	"math"
 	"testing"
+	"time"
 
 	"github.com/smpanaro/time-series-compression/series"
 	"github.com/stretchr/testify/require"
 )
 
+func TestCompressor_Decompress(t *testing.T) {
+	points, err := series.FromFile("../fixtures/brew1.txt")
+	require.NoError(t, err)
+
+	for _, method := range AllMethods {
+		for _, interleave := range []bool{false, true} {
+			c := NewCompressorOptions(Options{Method: method, Interleave: interleave})
+			enc, err := c.Compress(points)
+			require.NoError(t, err, method)
+
+			dec, err := c.Decompress(enc)
+			require.NoError(t, err, method)
+			require.Equal(t, len(points), len(dec), method)
+			require.True(t, points.MilliEqual(dec), "%s interleave=%v", method, interleave)
+		}
+	}
+}
+
+func TestCompressor_Float64(t *testing.T) {
+	// More significant digits than a float32 can hold.
+	values := []float64{1234.567891, 1234.567892, -0.000001, 1_987.654321, 0, 1e-6, 1234.567891}
+	var points series.Points
+	for i, v := range values {
+		points = append(points, &series.Point{Time: time.UnixMilli(int64(1_000 + i*15)), Value: v})
+	}
+	require.NotEqual(t, float64(float32(values[0])), values[0])
+
+	for _, method := range AllMethods {
+		for _, interleave := range []bool{false, true} {
+			opts := Options{Method: method, Interleave: interleave, Precision: 1_000_000, Verify: VerifyStrict}
+			enc, err := NewCompressorOptions(opts).Compress(points)
+			require.NoError(t, err, "%s interleave=%v", method, interleave)
+
+			dec, err := Decompress(enc)
+			require.NoError(t, err, method)
+			require.True(t, points.Equal(dec), "%s interleave=%v", method, interleave)
+		}
+	}
+
+	// Lossless methods keep values that are not decimals, too.
+	points = series.Points{
+		{Time: time.UnixMilli(0), Value: math.Pi},
+		{Time: time.UnixMilli(1), Value: 0.1 + 0.2},
+		{Time: time.UnixMilli(2), Value: math.SmallestNonzeroFloat64},
+		{Time: time.UnixMilli(3), Value: -math.MaxFloat64},
+		{Time: time.UnixMilli(4), Value: math.Inf(1)},
+	}
+	for _, method := range []Method{Gorilla, Chimp, Chimp128, ALP} {
+		enc, err := NewCompressorOptions(Options{Method: method, Verify: VerifyStrict}).Compress(points)
+		require.NoError(t, err, method)
+
+		dec, err := Decompress(enc)
+		require.NoError(t, err, method)
+		require.True(t, points.Equal(dec), method)
+	}
+}
+
+func TestCompressor_DeltaOrder(t *testing.T) {
+	// Regular samples with occasional jitter.
+	var points series.Points
+	for i := 0; i < 500; i++ {
+		ms := int64(1_000 + i*100)
+		if i%50 == 0 {
+			ms -= 3
+		}
+		points = append(points, &series.Point{Time: time.UnixMilli(ms), Value: float64(i%40) / 4})
+	}
+
+	sizes := map[int]int{}
+	for _, method := range []Method{Simple8b, BP32, CSV, ZstdCSV} {
+		for timeOrder := 1; timeOrder <= MaxDeltaOrder; timeOrder++ {
+			for valueOrder := 1; valueOrder <= MaxDeltaOrder; valueOrder++ {
+				for _, interleave := range []bool{false, true} {
+					opts := Options{Method: method, Interleave: interleave, TimeDeltaOrder: timeOrder, ValueDeltaOrder: valueOrder, Verify: VerifyStrict}
+					enc, err := NewCompressorOptions(opts).Compress(points)
+					require.NoError(t, err, "%s time=%d value=%d", method, timeOrder, valueOrder)
+
+					h, _, err := ReadHeader(enc)
+					require.NoError(t, err)
+					require.Equal(t, timeOrder, h.TimeDeltaOrder)
+					require.Equal(t, valueOrder, h.ValueDeltaOrder)
+
+					dec, err := Decompress(enc)
+					require.NoError(t, err)
+					require.True(t, points.Equal(dec))
+
+					if method == Simple8b && valueOrder == 1 && !interleave {
+						sizes[timeOrder] = len(enc)
+					}
+				}
+			}
+		}
+	}
+	// Delta-of-delta timestamps are mostly zero.
+	require.Less(t, sizes[2], sizes[1])
+
+	for _, order := range []int{-1, MaxDeltaOrder + 1} {
+		_, err := NewCompressorOptions(Options{Method: Simple8b, TimeDeltaOrder: order}).Compress(points)
+		require.Error(t, err)
+	}
+}
+
+func TestCompressor_UnsortedTimes(t *testing.T) {
+	unsorted := series.Points{
+		{Time: time.UnixMilli(2_000), Value: 1},
+		{Time: time.UnixMilli(1_000), Value: 2},
+	}
+	before1970 := series.Points{
+		{Time: time.UnixMilli(-1_000), Value: 1},
+		{Time: time.UnixMilli(1_000), Value: 2},
+	}
+
+	for _, method := range []Method{Simple8b, BP32, FastPFOR, PFORDelta, ZstdVarint, ZstdFixed} {
+		for _, points := range []series.Points{unsorted, before1970} {
+			// First order time deltas are stored unsigned.
+			_, err := NewCompressor(method).Compress(points)
+			require.ErrorContains(t, err, "time delta order of 2", method)
+
+			opts := Options{Method: method, TimeDeltaOrder: 2, Verify: VerifyStrict}
+			_, err = NewCompressorOptions(opts).Compress(points)
+			require.NoError(t, err, method)
+		}
+	}
+}
+
+func TestCompressor_Simple8bLimit(t *testing.T) {
+	points, err := series.FromFile("../fixtures/brew2.txt")
+	require.NoError(t, err)
+
+	// Absolute nanosecond timestamps are above 2^60.
+	_, err = NewCompressorOptions(Options{Method: Simple8b, Resolution: series.Nanosecond}).Compress(points)
+	require.ErrorContains(t, err, "at ns resolution")
+	require.Less(t, len(err.Error()), 200)
+
+	_, err = encodeSimple8b([]uint64{1, 2, 1 << 60})
+	require.ErrorContains(t, err, "integer 2 is")
+}
+
+func TestCompressor_BP32(t *testing.T) {
+	points, err := series.FromFile("../fixtures/brew1.txt")
+	require.NoError(t, err)
+	// A gap and a value too large for 32 bits in the middle of a block.
+	points[200].Value = 5e6
+	for _, pt := range points[300:] {
+		pt.Time = pt.Time.Add(100 * 24 * time.Hour)
+	}
+
+	for _, n := range []int{1, 2, 129, len(points)} {
+		for _, interleave := range []bool{false, true} {
+			c := NewCompressorOptions(Options{Method: BP32, Interleave: interleave, Verify: VerifyOn})
+			enc, err := c.Compress(points[:n])
+			require.NoError(t, err, "n=%d interleave=%v", n, interleave)
+
+			dec, err := c.Decompress(enc)
+			require.NoError(t, err)
+			require.True(t, points[:n].MilliEqual(dec), "n=%d interleave=%v", n, interleave)
+		}
+	}
+
+	// The first timestamp is absolute and round trips at every resolution.
+	for _, resolution := range series.AllResolutions {
+		rounded := points.Rounded(resolution, series.MilliPrecision)
+		enc, err := NewCompressorOptions(Options{Method: BP32, Resolution: resolution, Verify: VerifyStrict}).Compress(rounded)
+		require.NoError(t, err, resolution)
+
+		dec, err := Decompress(enc)
+		require.NoError(t, err)
+		require.True(t, rounded[0].Equal(dec[0]), resolution)
+		require.True(t, rounded.Equal(dec), resolution)
+	}
+
+	for _, input := range [][]int32{
+		{127},
+		{128},
+		{128, 33 << 24},
+		{128, 1<<24 | 1<<16 | 1<<8 | 1, 0, 0, 0},
+	} {
+		require.Error(t, checkBP32Blocks(input), "%v", input)
+	}
+	require.NoError(t, checkBP32Blocks([]int32{0}))
+	require.NoError(t, checkBP32Blocks([]int32{128, 1 << 24, 0}))
+}
+
 func BenchmarkCompressor_compressBrotli(t *testing.B) {
 	c := NewCompressor(Method(""))
 	points, err := series.FromFile("../fixtures/brew1.txt")
 	require.NoError(t, err)
 
-	csv := c.deltaCSV(points)
+	csv := c.csvEncoder.deltaCSV(points)
 	require.Greater(t, csv.Len(), 0)
 
 	for i := 0; i < t.N; i++ {
@@ -31,7 +216,7 @@ func BenchmarkCompressor_compressGzip(t *testing.B) {
 	points, err := series.FromFile("../fixtures/brew1.txt")
 	require.NoError(t, err)
 
-	csv := c.deltaCSV(points)
+	csv := c.csvEncoder.deltaCSV(points)
 	require.Greater(t, csv.Len(), 0)
 
 	for i := 0; i < t.N; i++ {
@@ -47,7 +232,7 @@ func BenchmarkCompressor_compressZlib(t *testing.B) {
 	points, err := series.FromFile("../fixtures/brew1.txt")
 	require.NoError(t, err)
 
-	csv := c.deltaCSV(points)
+	csv := c.csvEncoder.deltaCSV(points)
 	require.Greater(t, csv.Len(), 0)
 
 	for i := 0; i < t.N; i++ {
@@ -61,7 +246,7 @@ func BenchmarkCompressor_compressZstd(t *testing.B) {
 	points, err := series.FromFile("../fixtures/brew1.txt")
 	require.NoError(t, err)
 
-	csv := c.deltaCSV(points)
+	csv := c.csvEncoder.deltaCSV(points)
 	require.Greater(t, csv.Len(), 0)
 
 	for i := 0; i < t.N; i++ {
@@ -79,7 +264,7 @@ func BenchmarkCompressor_compressLzfse(t *testing.B) {
 	points, err := series.FromFile("../fixtures/brew1.txt")
 	require.NoError(t, err)
 
-	csv := c.deltaCSV(points)
+	csv := c.csvEncoder.deltaCSV(points)
 	require.Greater(t, csv.Len(), 0)
 
 	for i := 0; i < t.N; i++ {
@@ -93,7 +278,7 @@ func BenchmarkCompressor_compressLzma(t *testing.B) {
 	points, err := series.FromFile("../fixtures/brew1.txt")
 	require.NoError(t, err)
 
-	csv := c.deltaCSV(points)
+	csv := c.csvEncoder.deltaCSV(points)
 	require.Greater(t, csv.Len(), 0)
 
 	for i := 0; i < t.N; i++ {
@@ -102,3 +287,43 @@ func BenchmarkCompressor_compressLzma(t *testing.B) {
 	// Level 9: 17 ms/op
 	// Level 6: 12.6 ms/op (the libCompression header claims this is what iOS uses)
 }
+
+func TestCompressor_BinaryLayouts(t *testing.T) {
+	points, err := series.FromFile("../fixtures/brew2.txt")
+	require.NoError(t, err)
+
+	for _, interleave := range []bool{false, true} {
+		c := NewCompressorOptions(Options{Interleave: interleave})
+
+		fixed, err := c.binary(points, fixedLayout)
+		require.NoError(t, err)
+		require.Equal(t, 16*len(points), fixed.Len())
+		varint, err := c.binary(points, varintLayout)
+		require.NoError(t, err)
+		require.Less(t, varint.Len(), fixed.Len())
+
+		for _, layout := range []binaryLayout{varintLayout, fixedLayout} {
+			buf, err := c.binary(points, layout)
+			require.NoError(t, err)
+			dec, err := c.undoBinary(buf.Bytes(), layout)
+			require.NoError(t, err)
+			require.True(t, points.MilliEqual(dec), "layout=%d interleave=%v", layout, interleave)
+		}
+	}
+
+	// A single point is only a few bytes, less than some backends' headers.
+	for _, codec := range binaryCodecs() {
+		for _, n := range []int{1, 2} {
+			enc, err := NewCompressorOptions(Options{Method: codec.method, Verify: VerifyOn}).Compress(points[:n])
+			require.NoError(t, err, "%s n=%d", codec.method, n)
+			dec, err := Decompress(enc)
+			require.NoError(t, err)
+			require.True(t, points[:n].MilliEqual(dec), "%s n=%d", codec.method, n)
+		}
+	}
+
+	_, err = NewCompressor(ZstdFixed).undoBinary(make([]byte, 12), fixedLayout)
+	require.Error(t, err)
+	_, err = NewCompressor(ZstdVarint).undoBinary([]byte{0x80}, varintLayout)
+	require.Error(t, err)
+}
@@ -0,0 +1,268 @@
+package compress
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"math"
+	"time"
+
+	"github.com/smpanaro/time-series-compression/series"
+)
+
+// Compressed data is wrapped in a small container so it can be decompressed
+// without knowing how it was produced. The layout is:
+//
+//	magic     "TSC"
+//	version   uint8
+//	method    uvarint length + name
+//	flags     uint8, bit 0 is interleave, bits 1-2 are the resolution,
+//	          bits 3-4 and 5-6 are the time and value delta orders minus one
+//	          and bit 7 is set if more flags follow
+//	flags2    uint8, only present if bit 7 of flags is set. bits 0-1 are the
+//	          shuffle
+//	precision uvarint, values are stored as integer multiples of 1/precision
+//	count     uvarint, number of points
+//	start     varint, first timestamp in resolution units
+//	end       varint, last timestamp in resolution units
+//	checksum  uint32 little endian, CRC32 (IEEE) of the payload
+//	payload   the Codec output
+const (
+	containerMagic   = "TSC"
+	containerVersion = 1
+
+	flagInterleave = 1 << 0
+
+	flagResolutionShift = 1
+	flagResolutionMask  = 0b11 << flagResolutionShift
+
+	flagTimeDeltaShift  = 3
+	flagValueDeltaShift = 5
+	flagDeltaMask       = 0b11
+
+	flagExtended = 1 << 7
+
+	flag2ShuffleMask = 0b11
+)
+
+// resolutionFlags are the flag bits for each supported resolution.
+// Milliseconds are zero so the default is recorded without any flags.
+var resolutionFlags = map[series.Resolution]uint8{
+	series.Millisecond: 0,
+	series.Second:      1,
+	series.Microsecond: 2,
+	series.Nanosecond:  3,
+}
+
+var (
+	ErrNotContainer     = errors.New("not a compressed container")
+	ErrChecksumMismatch = errors.New("payload checksum mismatch")
+	ErrNoPoints         = errors.New("no points to compress")
+)
+
+// Header describes the payload of a container.
+type Header struct {
+	Version    uint8
+	Method     Method
+	Interleave bool
+	Resolution series.Resolution
+	Precision  int64
+	// TimeDeltaOrder and ValueDeltaOrder are always between 1 and MaxDeltaOrder.
+	TimeDeltaOrder  int
+	ValueDeltaOrder int
+	Shuffle         Shuffle
+	Count           int
+	Start           time.Time
+	End             time.Time
+	Checksum        uint32
+}
+
+// Options returns the Options needed to decode the payload.
+func (h Header) Options() Options {
+	return Options{
+		Method:          h.Method,
+		Interleave:      h.Interleave,
+		Resolution:      h.Resolution,
+		Precision:       h.Precision,
+		TimeDeltaOrder:  h.TimeDeltaOrder,
+		ValueDeltaOrder: h.ValueDeltaOrder,
+		Shuffle:         h.Shuffle,
+	}
+}
+
+func newHeader(opts Options, points series.Points, payload []byte) Header {
+	h := Header{
+		Version:         containerVersion,
+		Method:          opts.Method,
+		Interleave:      opts.Interleave,
+		Resolution:      opts.resolution(),
+		Precision:       opts.precision(),
+		TimeDeltaOrder:  opts.timeDeltaOrder(),
+		ValueDeltaOrder: opts.valueDeltaOrder(),
+		Shuffle:         opts.Shuffle,
+		Count:           len(points),
+		Checksum:        crc32.ChecksumIEEE(payload),
+	}
+	if len(points) > 0 {
+		h.Start = points[0].Time
+		h.End = points[len(points)-1].Time
+	}
+	return h
+}
+
+func (h Header) append(b []byte) []byte {
+	b = append(b, containerMagic...)
+	b = append(b, h.Version)
+	b = binary.AppendUvarint(b, uint64(len(h.Method)))
+	b = append(b, h.Method...)
+
+	var flags uint8
+	if h.Interleave {
+		flags |= flagInterleave
+	}
+	flags |= resolutionFlags[h.Resolution] << flagResolutionShift
+	flags |= uint8(h.TimeDeltaOrder-1) & flagDeltaMask << flagTimeDeltaShift
+	flags |= uint8(h.ValueDeltaOrder-1) & flagDeltaMask << flagValueDeltaShift
+	// Only write the second flags byte when needed so older containers are
+	// unchanged.
+	flags2 := uint8(h.Shuffle) & flag2ShuffleMask
+	if flags2 != 0 {
+		flags |= flagExtended
+	}
+	b = append(b, flags)
+	if flags2 != 0 {
+		b = append(b, flags2)
+	}
+
+	b = binary.AppendUvarint(b, uint64(h.Precision))
+	b = binary.AppendUvarint(b, uint64(h.Count))
+	b = binary.AppendVarint(b, h.Resolution.Unix(h.Start))
+	b = binary.AppendVarint(b, h.Resolution.Unix(h.End))
+	return binary.LittleEndian.AppendUint32(b, h.Checksum)
+}
+
+// IsContainer reports whether b starts with the container magic bytes.
+func IsContainer(b []byte) bool {
+	return bytes.HasPrefix(b, []byte(containerMagic))
+}
+
+// ReadHeader parses the container header at the start of b and returns it
+// along with the payload. The payload checksum is verified.
+func ReadHeader(b []byte) (Header, []byte, error) {
+	if !IsContainer(b) {
+		return Header{}, nil, ErrNotContainer
+	}
+	r := bytes.NewReader(b[len(containerMagic):])
+
+	var h Header
+	var err error
+	if h.Version, err = r.ReadByte(); err != nil {
+		return Header{}, nil, fmt.Errorf("reading version: %w", err)
+	}
+	if h.Version != containerVersion {
+		return Header{}, nil, fmt.Errorf("unsupported container version: %d", h.Version)
+	}
+
+	methodLen, err := binary.ReadUvarint(r)
+	if err != nil {
+		return Header{}, nil, fmt.Errorf("reading method: %w", err)
+	}
+	if methodLen > uint64(r.Len()) {
+		return Header{}, nil, fmt.Errorf("invalid method length: %d", methodLen)
+	}
+	method := make([]byte, methodLen)
+	if _, err := r.Read(method); err != nil {
+		return Header{}, nil, fmt.Errorf("reading method: %w", err)
+	}
+	h.Method = Method(method)
+
+	flags, err := r.ReadByte()
+	if err != nil {
+		return Header{}, nil, fmt.Errorf("reading flags: %w", err)
+	}
+	h.Interleave = flags&flagInterleave != 0
+	resolutionFlag := (flags & flagResolutionMask) >> flagResolutionShift
+	for resolution, flag := range resolutionFlags {
+		if flag == resolutionFlag {
+			h.Resolution = resolution
+		}
+	}
+	h.TimeDeltaOrder = int(flags>>flagTimeDeltaShift&flagDeltaMask) + 1
+	h.ValueDeltaOrder = int(flags>>flagValueDeltaShift&flagDeltaMask) + 1
+	if flags&flagExtended != 0 {
+		flags2, err := r.ReadByte()
+		if err != nil {
+			return Header{}, nil, fmt.Errorf("reading flags: %w", err)
+		}
+		h.Shuffle = Shuffle(flags2 & flag2ShuffleMask)
+		if _, ok := shuffleNames[h.Shuffle]; !ok {
+			return Header{}, nil, fmt.Errorf("unsupported shuffle: %s", h.Shuffle)
+		}
+	}
+
+	precision, err := binary.ReadUvarint(r)
+	if err != nil {
+		return Header{}, nil, fmt.Errorf("reading precision: %w", err)
+	}
+	if precision == 0 || precision > math.MaxInt64 {
+		return Header{}, nil, fmt.Errorf("invalid precision: %d", precision)
+	}
+	h.Precision = int64(precision)
+	count, err := binary.ReadUvarint(r)
+	if err != nil {
+		return Header{}, nil, fmt.Errorf("reading count: %w", err)
+	}
+	h.Count = int(count)
+
+	start, err := binary.ReadVarint(r)
+	if err != nil {
+		return Header{}, nil, fmt.Errorf("reading start: %w", err)
+	}
+	end, err := binary.ReadVarint(r)
+	if err != nil {
+		return Header{}, nil, fmt.Errorf("reading end: %w", err)
+	}
+	h.Start, h.End = h.Resolution.Time(start), h.Resolution.Time(end)
+
+	if err := binary.Read(r, binary.LittleEndian, &h.Checksum); err != nil {
+		return Header{}, nil, fmt.Errorf("reading checksum: %w", err)
+	}
+
+	payload := b[len(b)-r.Len():]
+	if crc32.ChecksumIEEE(payload) != h.Checksum {
+		return Header{}, nil, ErrChecksumMismatch
+	}
+
+	return h, payload, nil
+}
+
+// Decompress decodes a container produced by Compressor.Compress using the
+// method and options recorded in its header.
+func Decompress(b []byte) (series.Points, error) {
+	h, payload, err := ReadHeader(b)
+	if err != nil {
+		return nil, err
+	}
+	return decodePayload(h, payload, nil)
+}
+
+// decodePayload decodes payload with the options in h. dict is only needed by
+// ZstdDictCSV payloads compressed with a dictionary.
+func decodePayload(h Header, payload []byte, dict *Dictionary) (series.Points, error) {
+	codec, err := lookup(h.Method)
+	if err != nil {
+		return nil, err
+	}
+	opts := h.Options()
+	opts.Dictionary = dict
+	points, err := codec.Decode(payload, opts)
+	if err != nil {
+		return nil, err
+	}
+	if len(points) != h.Count {
+		return nil, fmt.Errorf("decoded %d points, header has %d", len(points), h.Count)
+	}
+	return points, nil
+}
@@ -0,0 +1,119 @@
+package compress
+
+import (
+	"testing"
+	"time"
+
+	"github.com/smpanaro/time-series-compression/series"
+	"github.com/stretchr/testify/require"
+)
+
+func TestContainer(t *testing.T) {
+	points, err := series.FromFile("../fixtures/brew2.txt")
+	require.NoError(t, err)
+
+	enc, err := NewCompressorOptions(Options{Method: Simple8b, Interleave: true}).Compress(points)
+	require.NoError(t, err)
+	require.True(t, IsContainer(enc))
+
+	h, payload, err := ReadHeader(enc)
+	require.NoError(t, err)
+	require.Equal(t, Simple8b, h.Method)
+	require.True(t, h.Interleave)
+	require.Equal(t, int64(series.MilliPrecision), h.Precision)
+	require.Equal(t, len(points), h.Count)
+	require.Equal(t, points[0].TimeMilli(), h.Start.UnixMilli())
+	require.Equal(t, points[len(points)-1].TimeMilli(), h.End.UnixMilli())
+
+	// The header determines how to decode, not the Compressor.
+	dec, err := NewCompressor(GzipCSV).Decompress(enc)
+	require.NoError(t, err)
+	require.True(t, points.MilliEqual(dec))
+
+	// Bare payloads are decoded with the Compressor's options.
+	dec, err = NewCompressorOptions(h.Options()).Decompress(payload)
+	require.NoError(t, err)
+	require.True(t, points.MilliEqual(dec))
+}
+
+func TestContainer_Precision(t *testing.T) {
+	points := series.Points{
+		{Time: time.UnixMilli(1_000), Value: 1.234567},
+		{Time: time.UnixMilli(2_000), Value: 1.5},
+		{Time: time.UnixMilli(3_000), Value: -2},
+	}
+
+	for _, method := range []Method{Simple8b, CSV, ZstdCSV} {
+		for precision, want := range map[int64]float64{1: 1, 1_000_000: 1.234567} {
+			enc, err := NewCompressorOptions(Options{Method: method, Precision: precision, Verify: VerifyOn}).Compress(points)
+			require.NoError(t, err)
+
+			h, _, err := ReadHeader(enc)
+			require.NoError(t, err)
+			require.Equal(t, precision, h.Precision)
+
+			// Decoding uses the precision in the header.
+			dec, err := NewCompressor(method).Decompress(enc)
+			require.NoError(t, err)
+			require.Equal(t, want, dec[0].Value, "%s precision=%d", method, precision)
+		}
+	}
+
+	_, err := NewCompressorOptions(Options{Method: Simple8b, Precision: -1}).Compress(points)
+	require.Error(t, err)
+}
+
+func TestContainer_Resolution(t *testing.T) {
+	points := series.Points{
+		{Time: time.Unix(1_691_161_006, 379_123_456), Value: 17.52},
+		{Time: time.Unix(1_691_161_006, 394_000_001), Value: 17.5},
+		{Time: time.Unix(1_691_161_007, 513_999_999), Value: 17.53},
+	}
+
+	for _, method := range []Method{Gorilla, ALP, CSV, ZstdCSV} {
+		for _, resolution := range series.AllResolutions {
+			enc, err := NewCompressorOptions(Options{Method: method, Resolution: resolution, Verify: VerifyOn}).Compress(points)
+			require.NoError(t, err, "%s resolution=%s", method, resolution)
+
+			h, _, err := ReadHeader(enc)
+			require.NoError(t, err)
+			require.Equal(t, resolution, h.Resolution)
+			require.Equal(t, points[0].TimeUnix(resolution), resolution.Unix(h.Start))
+
+			dec, err := NewCompressor(method).Decompress(enc)
+			require.NoError(t, err)
+			require.True(t, dec[2].Time.Equal(resolution.Time(points[2].TimeUnix(resolution))), "%s resolution=%s", method, resolution)
+		}
+	}
+
+	_, err := NewCompressorOptions(Options{Method: CSV, Resolution: series.Resolution(time.Minute)}).Compress(points)
+	require.Error(t, err)
+}
+
+func TestContainer_Corrupt(t *testing.T) {
+	points, err := series.FromFile("../fixtures/brew2.txt")
+	require.NoError(t, err)
+
+	enc, err := NewCompressor(ZlibCSV).Compress(points)
+	require.NoError(t, err)
+
+	corrupt := append([]byte(nil), enc...)
+	corrupt[len(corrupt)-1] ^= 0xFF
+	_, err = Decompress(corrupt)
+	require.ErrorIs(t, err, ErrChecksumMismatch)
+
+	_, err = Decompress(enc[:10])
+	require.Error(t, err)
+
+	_, err = Decompress([]byte("timestamp,weight"))
+	require.ErrorIs(t, err, ErrNotContainer)
+}
+
+func TestContainer_Empty(t *testing.T) {
+	// The codecs cannot decode an empty payload, so an empty series is
+	// rejected rather than written to a container that cannot be read.
+	for _, method := range AllMethods {
+		_, err := NewCompressor(method).Compress(series.Points{})
+		require.ErrorIs(t, err, ErrNoPoints, method)
+	}
+}
@@ -5,24 +5,68 @@ import (
 	"encoding/csv"
 	"fmt"
 	"strconv"
-	"time"
 
 	"github.com/smpanaro/time-series-compression/series"
 )
 
-// CSVPointEncoder encodes Points as CSV.
-type CSVPointEncoder struct{}
+// CSVPointEncoder encodes Points as CSV. Its fields have the same meaning and
+// defaults as the Options of the same name.
+type CSVPointEncoder struct {
+	Resolution      series.Resolution
+	Precision       int64
+	TimeDeltaOrder  int
+	ValueDeltaOrder int
+}
+
+func (c *CSVPointEncoder) options() Options {
+	return Options{
+		Resolution:      c.Resolution,
+		Precision:       c.Precision,
+		TimeDeltaOrder:  c.TimeDeltaOrder,
+		ValueDeltaOrder: c.ValueDeltaOrder,
+	}
+}
+
+func (c *CSVPointEncoder) resolution() series.Resolution {
+	return c.options().resolution()
+}
+
+func (c *CSVPointEncoder) precision() int64 {
+	return c.options().precision()
+}
+
+func (c *CSVPointEncoder) deltaEncoded(points series.Points) series.Points {
+	return c.options().deltaEncoded(points)
+}
+
+func (c *CSVPointEncoder) deltaDecoded(points series.Points) series.Points {
+	return c.options().deltaDecoded(points)
+}
 
 func (c *CSVPointEncoder) splitDeltaCSV(points series.Points) *bytes.Buffer {
+	return c.splitCSV(c.deltaEncoded(points))
+}
+
+func (c *CSVPointEncoder) undoSplitDeltaCSV(buf []byte) (series.Points, error) {
+	pts, err := c.undoSplitCSV(buf)
+	if err != nil {
+		return nil, err
+	}
+	return c.deltaDecoded(pts), nil
+}
+
+// splitCSV writes every timestamp and then every value, without delta
+// encoding them first.
+func (c *CSVPointEncoder) splitCSV(points series.Points) *bytes.Buffer {
 	var buf bytes.Buffer
 	s := csv.NewWriter(&buf)
 	s.Write([]string{"value"})
-	for _, pt := range points.DeltaEncoded(true, true) {
-		millisecondDelta := fmt.Sprintf("%v", pt.TimeMilli())
+	for _, pt := range points {
+		millisecondDelta := fmt.Sprintf("%v", pt.TimeUnix(c.resolution()))
 		s.Write([]string{millisecondDelta})
 	}
-	for _, pt := range points.DeltaEncoded(true, true) {
-		milligramsDelta := fmt.Sprintf("%v", pt.ValueMilli())
+	for _, pt := range points {
+		milligramsDelta := fmt.Sprintf("%v", pt.ValueScaled(c.precision()))
 		s.Write([]string{milligramsDelta})
 	}
 	s.Flush()
@@ -31,7 +75,7 @@ func (c *CSVPointEncoder) splitDeltaCSV(points series.Points) *bytes.Buffer {
 	return bytes.NewBuffer(bytes.TrimSpace(buf.Bytes()))
 }
 
-func (c *CSVPointEncoder) undoSplitDeltaCSV(buf []byte) (series.Points, error) {
+func (c *CSVPointEncoder) undoSplitCSV(buf []byte) (series.Points, error) {
 	lines, err := csv.NewReader(bytes.NewBuffer(buf)).ReadAll()
 	if err != nil {
 		return nil, err
@@ -62,21 +106,35 @@ func (c *CSVPointEncoder) undoSplitDeltaCSV(buf []byte) (series.Points, error) {
 			return nil, err
 		}
 		pts = append(pts, &series.Point{
-			Time:  time.UnixMilli(millisecondDelta),
-			Value: float32(milligramsDelta) / 1000,
+			Time:  c.resolution().Time(millisecondDelta),
+			Value: float64(milligramsDelta) / float64(c.precision()),
 		})
 	}
 
-	return pts.DeltaDecoded(true, true), nil
+	return pts, nil
 }
 
 func (c *CSVPointEncoder) deltaCSV(points series.Points) *bytes.Buffer {
+	return c.interleavedCSV(c.deltaEncoded(points))
+}
+
+func (c *CSVPointEncoder) undoDeltaCSV(buf []byte) (series.Points, error) {
+	pts, err := c.undoInterleavedCSV(buf)
+	if err != nil {
+		return nil, err
+	}
+	return c.deltaDecoded(pts), nil
+}
+
+// interleavedCSV writes a row for each point, without delta encoding them
+// first.
+func (c *CSVPointEncoder) interleavedCSV(points series.Points) *bytes.Buffer {
 	var buf bytes.Buffer
 	s := csv.NewWriter(&buf)
 	s.Write([]string{"millisecond delta", "milligram delta"})
-	for _, pt := range points.DeltaEncoded(true, true) {
-		millisecondDelta := fmt.Sprintf("%v", pt.TimeMilli())
-		milligramsDelta := fmt.Sprintf("%v", pt.ValueMilli())
+	for _, pt := range points {
+		millisecondDelta := fmt.Sprintf("%v", pt.TimeUnix(c.resolution()))
+		milligramsDelta := fmt.Sprintf("%v", pt.ValueScaled(c.precision()))
 		s.Write([]string{millisecondDelta, milligramsDelta})
 	}
 	s.Flush()
@@ -85,7 +143,7 @@ func (c *CSVPointEncoder) deltaCSV(points series.Points) *bytes.Buffer {
 	return bytes.NewBuffer(bytes.TrimSpace(buf.Bytes()))
 }
 
-func (c *CSVPointEncoder) undoDeltaCSV(buf []byte) (series.Points, error) {
+func (c *CSVPointEncoder) undoInterleavedCSV(buf []byte) (series.Points, error) {
 	lines, err := csv.NewReader(bytes.NewBuffer(buf)).ReadAll()
 	if err != nil {
 		return nil, err
@@ -109,10 +167,10 @@ func (c *CSVPointEncoder) undoDeltaCSV(buf []byte) (series.Points, error) {
 			return nil, err
 		}
 		pts = append(pts, &series.Point{
-			Time:  time.UnixMilli(millisecondDelta),
-			Value: float32(milligramsDelta) / 1000,
+			Time:  c.resolution().Time(millisecondDelta),
+			Value: float64(milligramsDelta) / float64(c.precision()),
 		})
 	}
 
-	return pts.DeltaDecoded(true, true), nil
+	return pts, nil
 }
@@ -0,0 +1,111 @@
+package compress
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+
+	"github.com/klauspost/compress/dict"
+	"github.com/smpanaro/time-series-compression/series"
+)
+
+// Dictionary is a zstd dictionary used by ZstdDictCSV. Small payloads have
+// little history for zstd to find matches in, so priming it with samples of
+// similar data recovers most of the ratio of compressing a whole series.
+type Dictionary struct {
+	// ID identifies the dictionary. It is stored in every ZstdDictCSV
+	// payload so decoding with the wrong dictionary fails early.
+	ID uint32
+	// Content is the dictionary in the zstd format, as written by the zstd
+	// CLI's --train.
+	Content []byte
+}
+
+// zstdDictMagic starts every zstd format dictionary. It is followed by the ID.
+const zstdDictMagic = 0xEC30A437
+
+// ParseDictionary reads a zstd format dictionary, such as one written by
+// TrainDictionary or `zstd --train`.
+func ParseDictionary(b []byte) (*Dictionary, error) {
+	if len(b) < 8 || binary.LittleEndian.Uint32(b) != zstdDictMagic {
+		return nil, errors.New("not a zstd dictionary")
+	}
+	id := binary.LittleEndian.Uint32(b[4:])
+	if id == 0 {
+		return nil, errors.New("invalid dictionary id: 0")
+	}
+	return &Dictionary{ID: id, Content: b}, nil
+}
+
+// DefaultDictionarySize is the default maximum size of a trained dictionary.
+const DefaultDictionarySize = 8 << 10
+
+// DictionarySamples splits every series into chunks of chunkSize points and
+// encodes each one as the CSV that ZstdDictCSV compresses with opts.
+func DictionarySamples(all []series.Points, opts Options, chunkSize int) [][]byte {
+	c := NewCompressorOptions(opts)
+	var samples [][]byte
+	for _, points := range all {
+		for start := 0; start < len(points); start += chunkSize {
+			end := start + chunkSize
+			if end > len(points) {
+				end = len(points)
+			}
+			samples = append(samples, c.csv(points[start:end]).Bytes())
+		}
+	}
+	return samples
+}
+
+// TrainDictionary builds a dictionary of at most maxSize bytes from samples,
+// typically from DictionarySamples. An id of 0 picks a random one.
+func TrainDictionary(samples [][]byte, maxSize int, id uint32) (*Dictionary, error) {
+	content, err := dict.BuildZstdDict(samples, dict.Options{
+		MaxDictSize: maxSize,
+		HashBytes:   6,
+		ZstdDictID:  id,
+		// The cgo backend bundles zstd 1.5.5.
+		ZstdDictCompat: true,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return ParseDictionary(content)
+}
+
+// compressZstdDictCSV prefixes the zstd frame with the little endian uint32
+// ID of the dictionary it was compressed with, or 0 if there is none.
+func (c *Compressor) compressZstdDictCSV(points series.Points) ([]byte, error) {
+	var id uint32
+	if c.opts.Dictionary != nil {
+		id = c.opts.Dictionary.ID
+	}
+	enc, err := c.compressZstdDict(c.csv(points), c.opts.Dictionary)
+	if err != nil {
+		return nil, err
+	}
+	return append(binary.LittleEndian.AppendUint32(make([]byte, 0, 4+len(enc)), id), enc...), nil
+}
+
+func (c *Compressor) decompressZstdDictCSV(b []byte) (series.Points, error) {
+	if len(b) < 4 {
+		return nil, fmt.Errorf("invalid %s length: %d", ZstdDictCSV, len(b))
+	}
+	var d *Dictionary
+	switch id := binary.LittleEndian.Uint32(b); {
+	case id == 0:
+		// Compressed without a dictionary.
+	case c.opts.Dictionary == nil:
+		return nil, fmt.Errorf("%s payload requires dictionary %d", ZstdDictCSV, id)
+	case c.opts.Dictionary.ID != id:
+		return nil, fmt.Errorf("%s payload requires dictionary %d, got %d", ZstdDictCSV, id, c.opts.Dictionary.ID)
+	default:
+		d = c.opts.Dictionary
+	}
+
+	decomp, err := c.decompressZstdDict(b[4:], d)
+	if err != nil {
+		return nil, err
+	}
+	return c.undoCSV(decomp)
+}
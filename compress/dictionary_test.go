@@ -0,0 +1,60 @@
+package compress
+
+import (
+	"testing"
+
+	"github.com/smpanaro/time-series-compression/series"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDictionary(t *testing.T) {
+	const chunkSize = 128
+
+	var training []series.Points
+	for _, path := range []string{"../fixtures/brew2.txt", "../fixtures/brew3.txt"} {
+		points, err := series.FromFile(path)
+		require.NoError(t, err)
+		training = append(training, points)
+	}
+	samples := DictionarySamples(training, Options{}, chunkSize)
+	require.Greater(t, len(samples), 10)
+	dict, err := TrainDictionary(samples, DefaultDictionarySize, 1234)
+	require.NoError(t, err)
+	require.Equal(t, uint32(1234), dict.ID)
+	require.LessOrEqual(t, len(dict.Content), DefaultDictionarySize)
+
+	parsed, err := ParseDictionary(dict.Content)
+	require.NoError(t, err)
+	require.Equal(t, dict, parsed)
+	_, err = ParseDictionary([]byte("not a dictionary"))
+	require.Error(t, err)
+
+	// Short chunks of an unseen series compress better with the dictionary.
+	points, err := series.FromFile("../fixtures/brew1.txt")
+	require.NoError(t, err)
+	c := NewCompressorOptions(Options{Method: ZstdDictCSV, Dictionary: dict, Verify: VerifyOn})
+	plainSize, dictSize := 0, 0
+	var enc []byte
+	for start := 0; start+chunkSize <= len(points); start += chunkSize {
+		chunk := points[start : start+chunkSize]
+		plain, err := NewCompressorOptions(Options{Method: ZstdCSV}).Compress(chunk)
+		require.NoError(t, err)
+		enc, err = c.Compress(chunk)
+		require.NoError(t, err)
+		plainSize += len(plain)
+		dictSize += len(enc)
+
+		dec, err := c.Decompress(enc)
+		require.NoError(t, err)
+		require.True(t, chunk.MilliEqual(dec))
+	}
+	require.Less(t, dictSize, plainSize)
+
+	// The dictionary is not in the header, so it must be given to decode.
+	_, err = Decompress(enc)
+	require.ErrorContains(t, err, "requires dictionary 1234")
+	other, err := TrainDictionary(samples, DefaultDictionarySize, 5678)
+	require.NoError(t, err)
+	_, err = NewCompressorOptions(Options{Dictionary: other}).Decompress(enc)
+	require.ErrorContains(t, err, "got 5678")
+}
@@ -0,0 +1,140 @@
+package compress
+
+import (
+	"fmt"
+	"math"
+	"math/bits"
+
+	"github.com/smpanaro/time-series-compression/series"
+)
+
+// gorillaEncoder XOR encodes values as described in "Gorilla: A Fast,
+// Scalable, In-Memory Time Series Database" (Pelkonen et al., 2015):
+//
+//	'0'                                     same as the previous value
+//	'10' + meaningful bits                  fits the previous leading/trailing zero window
+//	'11' + 5 bits leading zeros + 6 bits length + meaningful bits
+//
+// The first value is written in full.
+type gorillaEncoder struct {
+	w        *bitWriter
+	started  bool
+	prev     uint64
+	leading  int
+	trailing int
+}
+
+func newGorillaEncoder(w *bitWriter) *gorillaEncoder {
+	return &gorillaEncoder{w: w}
+}
+
+func (e *gorillaEncoder) write(f float64) {
+	v := math.Float64bits(f)
+	if !e.started {
+		e.w.writeBits(v, 64)
+		e.prev = v
+		e.started = true
+		// Force the first non-zero XOR to write its window.
+		e.leading = -1
+		return
+	}
+
+	xor := v ^ e.prev
+	e.prev = v
+	if xor == 0 {
+		e.w.writeBit(false)
+		return
+	}
+	e.w.writeBit(true)
+
+	leading := bits.LeadingZeros64(xor)
+	trailing := bits.TrailingZeros64(xor)
+	// Leading zeros are stored in 5 bits.
+	if leading > 31 {
+		leading = 31
+	}
+
+	if e.leading >= 0 && leading >= e.leading && trailing >= e.trailing {
+		e.w.writeBit(false)
+		e.w.writeBits(xor>>e.trailing, 64-e.leading-e.trailing)
+		return
+	}
+
+	e.leading, e.trailing = leading, trailing
+	meaningful := 64 - leading - trailing
+	e.w.writeBit(true)
+	e.w.writeBits(uint64(leading), 5)
+	// 64 meaningful bits does not fit in 6 bits; it is stored as 0.
+	e.w.writeBits(uint64(meaningful&63), 6)
+	e.w.writeBits(xor>>trailing, meaningful)
+}
+
+type gorillaDecoder struct {
+	r        *bitReader
+	started  bool
+	prev     uint64
+	leading  int
+	trailing int
+}
+
+func newGorillaDecoder(r *bitReader) *gorillaDecoder {
+	return &gorillaDecoder{r: r}
+}
+
+func (d *gorillaDecoder) read() (float64, error) {
+	if !d.started {
+		v, err := d.r.readBits(64)
+		if err != nil {
+			return 0, err
+		}
+		d.prev = v
+		d.started = true
+		return math.Float64frombits(v), nil
+	}
+
+	changed, err := d.r.readBit()
+	if err != nil {
+		return 0, err
+	}
+	if !changed {
+		return math.Float64frombits(d.prev), nil
+	}
+
+	newWindow, err := d.r.readBit()
+	if err != nil {
+		return 0, err
+	}
+	if newWindow {
+		leading, err := d.r.readBits(5)
+		if err != nil {
+			return 0, err
+		}
+		meaningful, err := d.r.readBits(6)
+		if err != nil {
+			return 0, err
+		}
+		if meaningful == 0 {
+			meaningful = 64
+		}
+		d.leading = int(leading)
+		d.trailing = 64 - int(leading) - int(meaningful)
+		if d.trailing < 0 {
+			return 0, fmt.Errorf("invalid gorilla window: %d leading, %d meaningful", leading, meaningful)
+		}
+	}
+
+	xor, err := d.r.readBits(64 - d.leading - d.trailing)
+	if err != nil {
+		return 0, err
+	}
+	d.prev ^= xor << d.trailing
+	return math.Float64frombits(d.prev), nil
+}
+
+func (c *Compressor) compressGorilla(points series.Points) ([]byte, error) {
+	return c.compressXOR(points, func(w *bitWriter) floatEncoder { return newGorillaEncoder(w) })
+}
+
+func (c *Compressor) decompressGorilla(b []byte) (series.Points, error) {
+	return c.decompressXOR(b, func(r *bitReader) floatDecoder { return newGorillaDecoder(r) })
+}
@@ -0,0 +1,107 @@
+package compress
+
+import "fmt"
+
+// Levels is the range of compression levels a method supports.
+type Levels struct {
+	Min, Max int
+	// Default is used when Options.Level is nil.
+	Default int
+}
+
+// Contains reports whether level is between Min and Max.
+func (l Levels) Contains(level int) bool {
+	return level >= l.Min && level <= l.Max
+}
+
+// All returns every level from Min to Max.
+func (l Levels) All() []int {
+	levels := make([]int, 0, l.Max-l.Min+1)
+	for level := l.Min; level <= l.Max; level++ {
+		levels = append(levels, level)
+	}
+	return levels
+}
+
+func (l Levels) String() string {
+	return fmt.Sprintf("%d..%d", l.Min, l.Max)
+}
+
+// Levels of the general purpose compressors. The zstd and brotli ranges match
+// the iOS app. The defaults are the levels used before they were configurable.
+var (
+	// compress/flate levels, shared by gzip and zlib. 0 stores without compressing.
+	flateLevels = Levels{Min: 0, Max: 9, Default: 5}
+	// Negative levels trade ratio for speed.
+	zstdLevels   = Levels{Min: -10, Max: 22, Default: 22}
+	brotliLevels = Levels{Min: 0, Max: 11, Default: 10}
+	// xz presets.
+	lzmaLevels = Levels{Min: 0, Max: 9, Default: 1}
+)
+
+var builtinLevels = map[Method]Levels{
+	ZstdCSV:     zstdLevels,
+	ZstdDictCSV: zstdLevels,
+	GzipCSV:     flateLevels,
+	ZlibCSV:     flateLevels,
+	BrotliCSV:   brotliLevels,
+	LzmaCSV:     lzmaLevels,
+
+	ZstdVarint:   zstdLevels,
+	ZstdFixed:    zstdLevels,
+	GzipVarint:   flateLevels,
+	GzipFixed:    flateLevels,
+	ZlibVarint:   flateLevels,
+	ZlibFixed:    flateLevels,
+	BrotliVarint: brotliLevels,
+	BrotliFixed:  brotliLevels,
+	LzmaVarint:   lzmaLevels,
+	LzmaFixed:    lzmaLevels,
+}
+
+// LevelCodec is implemented by codecs with configurable compression levels.
+type LevelCodec interface {
+	Codec
+	// Levels returns the levels the codec supports, or false if it has none.
+	Levels() (Levels, bool)
+}
+
+// MethodLevels returns the compression levels supported by method, or false
+// if it does not have levels.
+func MethodLevels(method Method) (Levels, bool) {
+	codec, ok := Lookup(method)
+	if !ok {
+		return Levels{}, false
+	}
+	if codec, ok := codec.(LevelCodec); ok {
+		return codec.Levels()
+	}
+	return Levels{}, false
+}
+
+// Level returns a pointer to level for use in Options.
+func Level(level int) *int {
+	return &level
+}
+
+func validateLevel(opts Options) error {
+	if opts.Level == nil {
+		return nil
+	}
+	levels, ok := MethodLevels(opts.Method)
+	if !ok {
+		return fmt.Errorf("%s does not support compression levels", opts.Method)
+	}
+	if !levels.Contains(*opts.Level) {
+		return fmt.Errorf("invalid level for %s: %d. must be in %s", opts.Method, *opts.Level, levels)
+	}
+	return nil
+}
+
+// level returns Options.Level, or the default of levels if it is not set.
+func (c *Compressor) level(levels Levels) int {
+	if c.opts.Level == nil {
+		return levels.Default
+	}
+	return *c.opts.Level
+}
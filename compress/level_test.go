@@ -0,0 +1,53 @@
+package compress
+
+import (
+	"testing"
+
+	"github.com/smpanaro/time-series-compression/series"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLevels(t *testing.T) {
+	points, err := series.FromFile("../fixtures/brew2.txt")
+	require.NoError(t, err)
+
+	for method, want := range builtinLevels {
+		if !AllMethods.Contains(method) {
+			continue
+		}
+		levels, ok := MethodLevels(method)
+		require.True(t, ok, method)
+		require.Equal(t, want, levels)
+
+		sizes := map[int]int{}
+		for _, level := range levels.All() {
+			enc, err := NewCompressorOptions(Options{Method: method, Level: Level(level), Verify: VerifyOn}).Compress(points)
+			require.NoError(t, err, "%s level=%d", method, level)
+			sizes[level] = len(enc)
+		}
+		if levels == lzmaLevels {
+			// The pure Go xz presets only set the dictionary size, which makes
+			// no difference to inputs smaller than the smallest one.
+			require.LessOrEqual(t, sizes[levels.Max], sizes[levels.Min], method)
+		} else {
+			require.Less(t, sizes[levels.Max], sizes[levels.Min], method)
+		}
+
+		// The default is the same as not setting a level.
+		enc, err := NewCompressor(method).Compress(points)
+		require.NoError(t, err)
+		require.Equal(t, sizes[levels.Default], len(enc), method)
+
+		for _, level := range []int{levels.Min - 1, levels.Max + 1} {
+			_, err := NewCompressorOptions(Options{Method: method, Level: Level(level)}).Compress(points)
+			require.Error(t, err, "%s level=%d", method, level)
+		}
+	}
+
+	for _, method := range []Method{Simple8b, Gorilla, CSV, LzfseCSV} {
+		_, ok := MethodLevels(method)
+		require.False(t, ok, method)
+	}
+	_, err = NewCompressorOptions(Options{Method: Simple8b, Level: Level(1)}).Compress(points)
+	require.Error(t, err)
+}
@@ -5,20 +5,47 @@ import "strings"
 type Method string
 
 const (
-	Simple8b  Method = "simple-8b"
-	Gorilla   Method = "gorilla"
-	BP32      Method = "bp32"
-	CSV       Method = "csv"
-	ZstdCSV   Method = "zstd-csv"
-	GzipCSV   Method = "gzip-csv"
-	ZlibCSV   Method = "zlib-csv"
-	BrotliCSV Method = "brotli-csv"
-	LzfseCSV  Method = "lzfse-csv"
-	LzmaCSV   Method = "lzma-csv"
+	Simple8b    Method = "simple-8b"
+	Gorilla     Method = "gorilla"
+	Chimp       Method = "chimp"
+	Chimp128    Method = "chimp128"
+	ALP         Method = "alp"
+	BP32        Method = "bp32"
+	FastPFOR    Method = "fastpfor"
+	PFORDelta   Method = "pfor-delta"
+	CSV         Method = "csv"
+	ZstdCSV     Method = "zstd-csv"
+	ZstdDictCSV Method = "zstd-dict-csv"
+	GzipCSV     Method = "gzip-csv"
+	ZlibCSV     Method = "zlib-csv"
+	BrotliCSV   Method = "brotli-csv"
+	LzfseCSV    Method = "lzfse-csv"
+	LzmaCSV     Method = "lzma-csv"
+	Lz4CSV      Method = "lz4-csv"
+	SnappyCSV   Method = "snappy-csv"
+
+	// Variants of the CSV methods that compress a binary layout instead.
+	ZstdVarint   Method = "zstd-varint"
+	ZstdFixed    Method = "zstd-fixed"
+	GzipVarint   Method = "gzip-varint"
+	GzipFixed    Method = "gzip-fixed"
+	ZlibVarint   Method = "zlib-varint"
+	ZlibFixed    Method = "zlib-fixed"
+	BrotliVarint Method = "brotli-varint"
+	BrotliFixed  Method = "brotli-fixed"
+	LzfseVarint  Method = "lzfse-varint"
+	LzfseFixed   Method = "lzfse-fixed"
+	LzmaVarint   Method = "lzma-varint"
+	LzmaFixed    Method = "lzma-fixed"
+	Lz4Varint    Method = "lz4-varint"
+	Lz4Fixed     Method = "lz4-fixed"
+	SnappyVarint Method = "snappy-varint"
+	SnappyFixed  Method = "snappy-fixed"
 )
 
 var (
-	AllMethods = Methods{Simple8b, Gorilla, BP32, CSV, ZstdCSV, GzipCSV, ZlibCSV, BrotliCSV, LzfseCSV, LzmaCSV}
+	// AllMethods lists every registered Codec's Method in registration order.
+	AllMethods Methods
 )
 
 func (x Method) String() string {
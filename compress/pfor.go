@@ -0,0 +1,494 @@
+package compress
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"math/bits"
+
+	"github.com/dataence/encoding/composition"
+	"github.com/dataence/encoding/cursor"
+	"github.com/dataence/encoding/fastpfor"
+	"github.com/dataence/encoding/variablebyte"
+	"github.com/smpanaro/time-series-compression/series"
+)
+
+// The FastPFOR and PFOR-delta methods bit pack the same delta encoded
+// integers as simple-8b (see Compressor.flatten) in blocks, storing the
+// few values that would widen a block as exceptions. FastPFOR ("Decoding
+// billions of integers per second through vectorization", Lemire and Boytsov,
+// 2012) comes from the dataence library and packs 32-bit integers. PFOR-delta
+// ("Super-Scalar RAM-CPU Cache Compression", Zukowski et al., 2006) is
+// implemented here and packs 64-bit integers directly.
+
+func (c *Compressor) compressFastPFOR(points series.Points) ([]byte, error) {
+	flat, err := c.flatten(points)
+	if err != nil {
+		return nil, err
+	}
+	return encodeFastPFOR(flat)
+}
+
+func (c *Compressor) decompressFastPFOR(b []byte) (series.Points, error) {
+	flat, err := decodeFastPFOR(b)
+	if err != nil {
+		return nil, err
+	}
+	if len(flat)%2 != 0 {
+		return nil, fmt.Errorf("uneven number of values")
+	}
+	return c.unflatten(flat), nil
+}
+
+// encodeFastPFOR writes the outliers (see splitOutliers) followed by the
+// FastPFOR words.
+func encodeFastPFOR(flat []uint64) ([]byte, error) {
+	if len(flat) == 0 {
+		return nil, fmt.Errorf("no data")
+	}
+	input, outliers := splitOutliers(flat)
+
+	// FastPFOR only packs whole blocks of 128, so let variable byte
+	// encoding pick up the remainder.
+	encoder := composition.New(fastpfor.New(), variablebyte.New())
+	inpos := cursor.New()
+	outpos := cursor.New()
+	// A block is never packed in more bits than 32 per value, the input's
+	// size. Block metadata and padding exceptions to groups of 32 add less
+	// than a quarter of that, each page adds a few words and the variable
+	// byte remainder of at most 127 values takes 5 bytes per value.
+	output := make([]int32, 2*len(input)+1024)
+	if err := encoder.Compress(input, inpos, len(input), output, outpos); err != nil {
+		return nil, err
+	}
+	return appendWords(outliers, output[:outpos.Get()]), nil
+}
+
+func decodeFastPFOR(b []byte) ([]uint64, error) {
+	outliers, b, err := readOutliers(b)
+	if err != nil {
+		return nil, err
+	}
+	input, err := readWords(b)
+	if err != nil {
+		return nil, fmt.Errorf("invalid fastpfor length: %w", err)
+	}
+	if err := checkFastPFORPages(input); err != nil {
+		return nil, err
+	}
+
+	// Every variable byte encoded value takes at least one byte.
+	packed := int(uint32(input[0]))
+	decoder := composition.New(fastpfor.New(), variablebyte.New())
+	inpos := cursor.New()
+	outpos := cursor.New()
+	output := make([]int32, packed+4*len(input))
+	if err := decoder.Uncompress(input, inpos, len(input), output, outpos); err != nil {
+		return nil, err
+	}
+	return joinOutliers(output[:outpos.Get()], outliers)
+}
+
+// checkFastPFORPages checks that the pages of bit packed blocks at the start
+// of input fit in it and that their metadata is consistent, since the library
+// trusts both. A page is written as:
+//
+//	meta       offset from this word to the metadata
+//	packed     the blocks' values, 4 words per bit of width
+//	bytesize   length of the byte metadata
+//	bytes      per block its width, exception count and, if it has
+//	           exceptions, its widest value's width and their positions
+//	bitmap     bit k-1 is set if there are exceptions k bits wide
+//	exceptions per set bit, a count followed by the exceptions bit packed
+//	           in groups of 32
+func checkFastPFORPages(input []int32) error {
+	if len(input) == 0 {
+		return fmt.Errorf("invalid fastpfor length: 0 words")
+	}
+	packed := int(uint32(input[0]))
+	if packed%fastpfor.DefaultBlockSize != 0 {
+		return fmt.Errorf("invalid fastpfor block length: %d", packed)
+	}
+
+	pos := 1
+	for page := 0; packed > 0; page++ {
+		size := packed
+		if size > fastpfor.DefaultPageSize {
+			size = fastpfor.DefaultPageSize
+		}
+		packed -= size
+		blocks := size / fastpfor.DefaultBlockSize
+		if err := checkFastPFORPage(input, &pos, blocks); err != nil {
+			return fmt.Errorf("invalid fastpfor page %d: %w", page, err)
+		}
+	}
+	return nil
+}
+
+// checkFastPFORPage checks the page of blocks starting at *pos and advances
+// it past the page.
+func checkFastPFORPage(input []int32, pos *int, blocks int) error {
+	word := func(i int) (int, error) {
+		if i < 0 || i >= len(input) {
+			return 0, fmt.Errorf("truncated")
+		}
+		return int(input[i]), nil
+	}
+
+	meta, err := word(*pos)
+	if err != nil {
+		return err
+	}
+	data, metaPos := *pos+1, *pos+meta
+	bytesize, err := word(metaPos)
+	if err != nil || metaPos < data {
+		return fmt.Errorf("invalid metadata offset: %d", meta)
+	}
+	if bytesize < 0 || bytesize > 4*len(input) {
+		return fmt.Errorf("invalid metadata length: %d", bytesize)
+	}
+	bytesPos := metaPos + 1
+	readByte := func(i int) (int, error) {
+		if i >= bytesize {
+			return 0, fmt.Errorf("truncated metadata")
+		}
+		return int(uint32(input[bytesPos+i/4]) >> (24 - i%4*8) & 0xff), nil
+	}
+
+	next := bytesPos + (bytesize+3)/4
+	bitmap, err := word(next)
+	if err != nil {
+		return err
+	}
+	next++
+	var exceptions [33]int
+	for width := 1; width <= 32; width++ {
+		if uint32(bitmap)&(1<<(width-1)) == 0 {
+			continue
+		}
+		count, err := word(next)
+		if err != nil {
+			return err
+		}
+		if count < 0 || count > 32*len(input) {
+			return fmt.Errorf("invalid exception count: %d", count)
+		}
+		exceptions[width] = count
+		next += 1 + (count+31)/32*width
+		if next > len(input) {
+			return fmt.Errorf("truncated")
+		}
+	}
+
+	b := 0
+	for block := 0; block < blocks; block++ {
+		var header [2]int
+		for i := range header {
+			if header[i], err = readByte(b); err != nil {
+				return err
+			}
+			b++
+		}
+		width, count := header[0], header[1]
+		if width > 32 {
+			return fmt.Errorf("invalid bit width: %d", width)
+		}
+		data += 4 * width
+		if data > metaPos {
+			return fmt.Errorf("block %d is truncated", block)
+		}
+		if count == 0 {
+			continue
+		}
+
+		widest, err := readByte(b)
+		if err != nil {
+			return err
+		}
+		b++
+		if widest <= width || widest > 32 || count > exceptions[widest-width] {
+			return fmt.Errorf("invalid exceptions in block %d", block)
+		}
+		exceptions[widest-width] -= count
+		for i := 0; i < count; i++ {
+			position, err := readByte(b)
+			if err != nil {
+				return err
+			}
+			b++
+			if position >= fastpfor.DefaultBlockSize {
+				return fmt.Errorf("invalid exception position: %d", position)
+			}
+		}
+	}
+	*pos = next
+	return nil
+}
+
+// outlier is an integer that does not fit in the 32 bits FastPFOR and BP32
+// pack.
+type outlier struct {
+	index int
+	value uint64
+}
+
+// splitOutliers converts flat to 32-bit words. Outliers are written as zero
+// so they do not widen their block, and are returned as a uvarint count
+// followed by the uvarint gap to each outlier's index and its value. The
+// first timestamp is usually the only outlier.
+func splitOutliers(flat []uint64) ([]int32, []byte) {
+	words := make([]int32, len(flat))
+	var outliers []outlier
+	for i, v := range flat {
+		if v > math.MaxUint32 {
+			outliers = append(outliers, outlier{i, v})
+			continue
+		}
+		words[i] = int32(uint32(v))
+	}
+
+	buf := binary.AppendUvarint(nil, uint64(len(outliers)))
+	prev := 0
+	for _, o := range outliers {
+		buf = binary.AppendUvarint(buf, uint64(o.index-prev))
+		buf = binary.AppendUvarint(buf, o.value)
+		prev = o.index
+	}
+	return words, buf
+}
+
+// readOutliers reads the outliers written by splitOutliers and returns the
+// rest of b.
+func readOutliers(b []byte) ([]outlier, []byte, error) {
+	count, n := binary.Uvarint(b)
+	if n <= 0 || count > uint64(len(b)) {
+		return nil, nil, fmt.Errorf("invalid outlier count")
+	}
+	b = b[n:]
+	outliers := make([]outlier, count)
+	prev := uint64(0)
+	for i := range outliers {
+		gap, n := binary.Uvarint(b)
+		if n <= 0 {
+			return nil, nil, fmt.Errorf("invalid outlier index")
+		}
+		b = b[n:]
+		v, n := binary.Uvarint(b)
+		if n <= 0 {
+			return nil, nil, fmt.Errorf("invalid outlier value")
+		}
+		b = b[n:]
+		if prev+gap > math.MaxInt32 {
+			return nil, nil, fmt.Errorf("invalid outlier index")
+		}
+		prev += gap
+		outliers[i] = outlier{int(prev), v}
+	}
+	return outliers, b, nil
+}
+
+// joinOutliers reverses splitOutliers.
+func joinOutliers(words []int32, outliers []outlier) ([]uint64, error) {
+	flat := make([]uint64, len(words))
+	for i, w := range words {
+		flat[i] = uint64(uint32(w))
+	}
+	for _, o := range outliers {
+		if o.index >= len(flat) {
+			return nil, fmt.Errorf("invalid outlier index: %d", o.index)
+		}
+		flat[o.index] = o.value
+	}
+	return flat, nil
+}
+
+// appendWords appends words to buf as little endian uint32s.
+func appendWords(buf []byte, words []int32) []byte {
+	for _, w := range words {
+		buf = binary.LittleEndian.AppendUint32(buf, uint32(w))
+	}
+	return buf
+}
+
+func readWords(b []byte) ([]int32, error) {
+	if len(b) == 0 || len(b)%4 != 0 {
+		return nil, fmt.Errorf("%d bytes", len(b))
+	}
+	words := make([]int32, len(b)/4)
+	for i := range words {
+		words[i] = int32(binary.LittleEndian.Uint32(b[i*4:]))
+	}
+	return words, nil
+}
+
+// PFOR-delta writes the integer count followed by blocks of pforBlockSize
+// integers. Each block is written as:
+//
+//	width     8 bits, bits per packed offset
+//	high      8 bits, bits per exception's high bits
+//	base      64 bits, the frame of reference
+//	count     8 bits, number of exceptions
+//	packed    width bits per value, the low bits of its offset from base
+//	exception 7 bit position + high bits, for each exception
+//
+// Unlike ALP's exceptions, which replace a value, PFOR's patch the high bits
+// of an offset that does not fit in width bits.
+const (
+	pforBlockSize = 128
+
+	pforHeaderBits   = 8 + 8 + 64 + 8
+	pforPositionBits = 7
+)
+
+func (c *Compressor) compressPFORDelta(points series.Points) ([]byte, error) {
+	flat, err := c.flatten(points)
+	if err != nil {
+		return nil, err
+	}
+	return encodePFOR(flat), nil
+}
+
+func (c *Compressor) decompressPFORDelta(b []byte) (series.Points, error) {
+	flat, err := decodePFOR(b)
+	if err != nil {
+		return nil, err
+	}
+	if len(flat)%2 != 0 {
+		return nil, fmt.Errorf("uneven number of values")
+	}
+	return c.unflatten(flat), nil
+}
+
+func encodePFOR(flat []uint64) []byte {
+	w := &bitWriter{buf: binary.AppendUvarint(nil, uint64(len(flat)))}
+	for start := 0; start < len(flat); start += pforBlockSize {
+		end := start + pforBlockSize
+		if end > len(flat) {
+			end = len(flat)
+		}
+		writePFORBlock(w, flat[start:end])
+	}
+	return w.bytes()
+}
+
+func decodePFOR(b []byte) ([]uint64, error) {
+	count, n := binary.Uvarint(b)
+	if n <= 0 {
+		return nil, fmt.Errorf("invalid pfor count")
+	}
+	b = b[n:]
+	if blocks := (count + pforBlockSize - 1) / pforBlockSize; blocks > uint64(len(b))*8/pforHeaderBits {
+		return nil, fmt.Errorf("invalid pfor count: %d", count)
+	}
+
+	r := &bitReader{buf: b}
+	flat := make([]uint64, count)
+	for start := 0; start < len(flat); start += pforBlockSize {
+		end := start + pforBlockSize
+		if end > len(flat) {
+			end = len(flat)
+		}
+		if err := readPFORBlock(r, flat[start:end]); err != nil {
+			return nil, err
+		}
+	}
+	return flat, nil
+}
+
+// pforWidth returns the width that packs offsets in the fewest bits and the
+// number of bits above it in the widest offset.
+func pforWidth(offsets []uint64) (width, high int) {
+	var maximum uint64
+	for _, o := range offsets {
+		maximum |= o
+	}
+	widest := bits.Len64(maximum)
+
+	best := len(offsets) * widest
+	width = widest
+	for w := 0; w < widest; w++ {
+		size := len(offsets) * w
+		for _, o := range offsets {
+			if o>>w != 0 {
+				size += pforPositionBits + widest - w
+			}
+		}
+		if size < best {
+			best, width = size, w
+		}
+	}
+	return width, widest - width
+}
+
+func writePFORBlock(w *bitWriter, values []uint64) {
+	base := values[0]
+	for _, v := range values {
+		if v < base {
+			base = v
+		}
+	}
+	offsets := make([]uint64, len(values))
+	var exceptions []int
+	for i, v := range values {
+		offsets[i] = v - base
+	}
+	width, high := pforWidth(offsets)
+	for i, o := range offsets {
+		if o>>width != 0 {
+			exceptions = append(exceptions, i)
+		}
+	}
+
+	w.writeBits(uint64(width), 8)
+	w.writeBits(uint64(high), 8)
+	w.writeBits(base, 64)
+	w.writeBits(uint64(len(exceptions)), 8)
+	for _, o := range offsets {
+		w.writeBits(o, width)
+	}
+	for _, i := range exceptions {
+		w.writeBits(uint64(i), pforPositionBits)
+		w.writeBits(offsets[i]>>width, high)
+	}
+}
+
+func readPFORBlock(r *bitReader, values []uint64) error {
+	var header [4]uint64
+	for i, n := range []int{8, 8, 64, 8} {
+		v, err := r.readBits(n)
+		if err != nil {
+			return err
+		}
+		header[i] = v
+	}
+	width, high, base, exceptions := int(header[0]), int(header[1]), header[2], int(header[3])
+	if width+high > 64 || exceptions > len(values) {
+		return fmt.Errorf("invalid pfor block header: width=%d high=%d exceptions=%d", width, high, exceptions)
+	}
+
+	for i := range values {
+		v, err := r.readBits(width)
+		if err != nil {
+			return err
+		}
+		values[i] = v
+	}
+	for i := 0; i < exceptions; i++ {
+		pos, err := r.readBits(pforPositionBits)
+		if err != nil {
+			return err
+		}
+		v, err := r.readBits(high)
+		if err != nil {
+			return err
+		}
+		if int(pos) >= len(values) {
+			return fmt.Errorf("invalid pfor exception position: %d", pos)
+		}
+		values[pos] |= v << width
+	}
+	for i := range values {
+		values[i] += base
+	}
+	return nil
+}
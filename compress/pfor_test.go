@@ -0,0 +1,78 @@
+package compress
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPFOR_RoundTrip(t *testing.T) {
+	for _, n := range []int{2, 127, 128, 130, 1000} {
+		flat := make([]uint64, n)
+		for i := range flat {
+			flat[i] = uint64(i % 7)
+		}
+		// Outliers of every size, including the first absolute timestamp.
+		flat[0] = 1_691_161_006_379
+		flat[n/2] = math.MaxUint64
+		flat[n-1] = math.MaxUint32 + 1
+
+		enc, err := encodeFastPFOR(flat)
+		require.NoError(t, err)
+		dec, err := decodeFastPFOR(enc)
+		require.NoError(t, err)
+		require.Equal(t, flat, dec, "fastpfor n=%d", n)
+
+		dec, err = decodePFOR(encodePFOR(flat))
+		require.NoError(t, err)
+		require.Equal(t, flat, dec, "pfor n=%d", n)
+	}
+
+	// Patching keeps a block of small values narrow.
+	flat := make([]uint64, pforBlockSize)
+	flat[5] = math.MaxUint64
+	width, high := pforWidth(flat)
+	require.Equal(t, 0, width)
+	require.Equal(t, 64, high)
+	require.Less(t, len(encodePFOR(flat)), 32)
+}
+
+func TestPFOR_Invalid(t *testing.T) {
+	for _, b := range [][]byte{
+		nil,
+		{0},
+		{0, 1, 2, 3},
+		{1, 200, 1, 0, 0, 0, 0},
+		{0, 0xff, 0xff, 0xff, 0xff},
+	} {
+		_, err := decodeFastPFOR(b)
+		require.Error(t, err, "fastpfor %v", b)
+	}
+
+	// Corrupt every bit of a payload with exceptions and a remainder.
+	flat := make([]uint64, 300)
+	for i := range flat {
+		flat[i] = uint64(i % 7)
+	}
+	flat[0], flat[50], flat[200] = 1_691_161_006_379, 1<<20, 1<<31
+	enc, err := encodeFastPFOR(flat)
+	require.NoError(t, err)
+	for i := range enc {
+		for bit := 0; bit < 8; bit++ {
+			b := append([]byte(nil), enc...)
+			b[i] ^= 1 << bit
+			require.NotPanics(t, func() { decodeFastPFOR(b) }, "byte %d bit %d", i, bit)
+		}
+		require.NotPanics(t, func() { decodeFastPFOR(enc[:i]) }, "truncated to %d", i)
+	}
+
+	for _, b := range [][]byte{
+		nil,
+		{0xff, 0xff, 0xff, 0xff, 0x0f},
+		{1, 64, 1, 0, 0, 0, 0, 0, 0, 0, 0, 0},
+	} {
+		_, err := decodePFOR(b)
+		require.Error(t, err, "pfor %v", b)
+	}
+}
@@ -0,0 +1,322 @@
+package compress
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/smpanaro/time-series-compression/series"
+)
+
+// A Pipeline is a method declared as a chain of stages separated by "|", such
+// as "dod|zigzag|simple8b" or "delta|csv|zstd:19". Encoding runs the stages in
+// order and decoding runs their inverses in reverse. Any Method containing a
+// "|" is parsed as a pipeline by Lookup, so it does not need to be registered
+// and containers record the whole chain.
+//
+// Stages take points, integers or bytes and the chain must end in bytes.
+// Points are flattened into integers (see Options.Interleave) when an
+// integer stage follows them. The stages are:
+//
+//	delta[:n]   points  difference timestamps and values n times (default 1)
+//	dod         points  delta-of-delta timestamps and delta values
+//	flatten     points  to integers, added automatically when needed
+//	zigzag      ints    zigzag encode timestamps. values always are
+//	simple8b    ints    to bytes with simple-8b
+//	fastpfor    ints    to bytes with FastPFOR
+//	pfor        ints    to bytes with patched frame-of-reference
+//	varint      ints    to bytes as uvarints
+//	fixed       ints    to bytes as little endian uint64s
+//	shuffle     ints    to bytes as little endian uint64s grouped by byte
+//	bitshuffle  ints    to bytes grouped by bit
+//	csv         points  to bytes as CSV
+//	zstd[:level], gzip[:level], zlib[:level], brotli[:level],
+//	lzma[:level], lzfse, lz4, snappy
+//	            bytes   compress with a general purpose backend
+//
+// Times and values are rounded to Options.Resolution and Options.Precision
+// before the first stage. The delta orders come from the stages, so
+// Options.TimeDeltaOrder and ValueDeltaOrder must not be set.
+type Pipeline struct {
+	stages []stage
+}
+
+// PipelineSeparator separates the stages of a Pipeline.
+const PipelineSeparator = "|"
+
+type dataKind int
+
+const (
+	kindPoints dataKind = iota
+	kindInts
+	kindBytes
+)
+
+var dataKindNames = map[dataKind]string{
+	kindPoints: "points",
+	kindInts:   "integers",
+	kindBytes:  "bytes",
+}
+
+func (k dataKind) String() string {
+	return dataKindNames[k]
+}
+
+// pipelineData is the input or output of a stage. Only the field of the
+// stage's kind is set.
+type pipelineData struct {
+	points series.Points
+	ints   []uint64
+	bytes  []byte
+}
+
+type stage struct {
+	name    string
+	in, out dataKind
+	encode  func(c *Compressor, d pipelineData) (pipelineData, error)
+	decode  func(c *Compressor, d pipelineData) (pipelineData, error)
+}
+
+// ParsePipeline parses a chain of stages separated by PipelineSeparator.
+func ParsePipeline(s string) (*Pipeline, error) {
+	p := &Pipeline{}
+	kind := kindPoints
+	for _, spec := range strings.Split(s, PipelineSeparator) {
+		st, err := parseStage(strings.TrimSpace(spec))
+		if err != nil {
+			return nil, err
+		}
+		if kind == kindPoints && st.in == kindInts {
+			p.stages = append(p.stages, flattenStage)
+			kind = kindInts
+		}
+		if st.in != kind {
+			return nil, fmt.Errorf("pipeline stage %s takes %s, not %s", st.name, st.in, kind)
+		}
+		p.stages = append(p.stages, st)
+		kind = st.out
+	}
+	if kind != kindBytes {
+		return nil, fmt.Errorf("pipeline must end with a stage that outputs bytes, not %s", kind)
+	}
+	return p, nil
+}
+
+// Method returns the pipeline's stages joined by PipelineSeparator, without
+// any that were added automatically.
+func (p *Pipeline) Method() Method {
+	var names []string
+	for _, st := range p.stages {
+		if st.name != "" {
+			names = append(names, st.name)
+		}
+	}
+	return Method(strings.Join(names, PipelineSeparator))
+}
+
+func (p *Pipeline) Encode(points series.Points, opts Options) ([]byte, error) {
+	if opts.TimeDeltaOrder > 1 || opts.ValueDeltaOrder > 1 {
+		return nil, fmt.Errorf("%s: delta orders are set by the pipeline's delta stages", p.Method())
+	}
+	c := NewCompressorOptions(opts)
+	d := pipelineData{points: points.Rounded(opts.resolution(), opts.precision())}
+	for _, st := range p.stages {
+		var err error
+		if d, err = st.encode(c, d); err != nil {
+			return nil, fmt.Errorf("%s: %w", st.name, err)
+		}
+	}
+	return d.bytes, nil
+}
+
+func (p *Pipeline) Decode(b []byte, opts Options) (series.Points, error) {
+	c := NewCompressorOptions(opts)
+	d := pipelineData{bytes: b}
+	for i := len(p.stages) - 1; i >= 0; i-- {
+		var err error
+		if d, err = p.stages[i].decode(c, d); err != nil {
+			return nil, fmt.Errorf("%s: %w", p.stages[i].name, err)
+		}
+	}
+	return d.points.Rounded(opts.resolution(), opts.precision()), nil
+}
+
+// parseStage parses a stage name and its optional ":" separated argument.
+func parseStage(spec string) (stage, error) {
+	name, arg, hasArg := strings.Cut(spec, ":")
+	var n int
+	if hasArg {
+		var err error
+		if n, err = strconv.Atoi(arg); err != nil {
+			return stage{}, fmt.Errorf("invalid argument for pipeline stage %s: %s", name, arg)
+		}
+	}
+
+	if b, ok := pipelineBackends[name]; ok {
+		if !hasArg {
+			return backendStage(spec, b, nil), nil
+		}
+		if b.levels == nil {
+			return stage{}, fmt.Errorf("pipeline stage %s does not support levels", name)
+		}
+		if !b.levels.Contains(n) {
+			return stage{}, fmt.Errorf("invalid level for pipeline stage %s: %d. must be in %s", name, n, b.levels)
+		}
+		return backendStage(spec, b, Level(n)), nil
+	}
+
+	if name == "delta" {
+		order := 1
+		if hasArg {
+			order = n
+		}
+		if order < 1 || order > MaxDeltaOrder {
+			return stage{}, fmt.Errorf("invalid order for pipeline stage delta: %d. must be between 1 and %d", order, MaxDeltaOrder)
+		}
+		return deltaStage(spec, order, order), nil
+	}
+	if hasArg {
+		return stage{}, fmt.Errorf("pipeline stage %s does not take an argument", name)
+	}
+	switch name {
+	case "dod":
+		return deltaStage(spec, 2, 1), nil
+	case "flatten":
+		st := flattenStage
+		st.name = spec
+		return st, nil
+	case "zigzag":
+		return stage{
+			name: spec, in: kindInts, out: kindInts,
+			encode: func(c *Compressor, d pipelineData) (pipelineData, error) {
+				c.mapTimes(d.ints, func(v uint64) uint64 { return series.ZigZagEncode64(int64(v)) })
+				return d, nil
+			},
+			decode: func(c *Compressor, d pipelineData) (pipelineData, error) {
+				c.mapTimes(d.ints, func(v uint64) uint64 { return uint64(series.ZigZagDecode64(v)) })
+				return d, nil
+			},
+		}, nil
+	case "simple8b":
+		return intsStage(spec, encodeSimple8b, decodeSimple8b), nil
+	case "fastpfor":
+		return intsStage(spec, encodeFastPFOR, decodeFastPFOR), nil
+	case "pfor":
+		return intsStage(spec, func(flat []uint64) ([]byte, error) { return encodePFOR(flat), nil }, decodePFOR), nil
+	case "varint":
+		return intsStage(spec, func(flat []uint64) ([]byte, error) { return appendVarints(flat), nil }, readVarints), nil
+	case "fixed":
+		return intsStage(spec, func(flat []uint64) ([]byte, error) { return appendFixed(flat), nil }, readFixed), nil
+	case "shuffle":
+		return intsStage(spec, func(flat []uint64) ([]byte, error) { return shuffleBytes(flat), nil }, unshuffleBytes), nil
+	case "bitshuffle":
+		return intsStage(spec, func(flat []uint64) ([]byte, error) { return shuffleBits(flat), nil }, unshuffleBits), nil
+	case "csv":
+		return stage{
+			name: spec, in: kindPoints, out: kindBytes,
+			encode: func(c *Compressor, d pipelineData) (pipelineData, error) {
+				if c.interleave {
+					return pipelineData{bytes: c.csvEncoder.interleavedCSV(d.points).Bytes()}, nil
+				}
+				return pipelineData{bytes: c.csvEncoder.splitCSV(d.points).Bytes()}, nil
+			},
+			decode: func(c *Compressor, d pipelineData) (pipelineData, error) {
+				var points series.Points
+				var err error
+				if c.interleave {
+					points, err = c.csvEncoder.undoInterleavedCSV(d.bytes)
+				} else {
+					points, err = c.csvEncoder.undoSplitCSV(d.bytes)
+				}
+				return pipelineData{points: points}, err
+			},
+		}, nil
+	}
+	return stage{}, fmt.Errorf("unknown pipeline stage: %s", name)
+}
+
+// flattenStage has no name since it is usually added automatically.
+var flattenStage = stage{
+	in: kindPoints, out: kindInts,
+	encode: func(c *Compressor, d pipelineData) (pipelineData, error) {
+		return pipelineData{ints: d.points.Flatten(c.interleave, c.opts.resolution(), c.opts.precision())}, nil
+	},
+	decode: func(c *Compressor, d pipelineData) (pipelineData, error) {
+		if len(d.ints)%2 != 0 {
+			return pipelineData{}, fmt.Errorf("uneven number of values")
+		}
+		return pipelineData{points: series.FromFlat(d.ints, c.interleave, c.opts.resolution(), c.opts.precision())}, nil
+	},
+}
+
+func deltaStage(name string, timeOrder, valueOrder int) stage {
+	return stage{
+		name: name, in: kindPoints, out: kindPoints,
+		encode: func(c *Compressor, d pipelineData) (pipelineData, error) {
+			resolution := c.opts.resolution()
+			return pipelineData{points: d.points.
+				DeltaEncodedOrder(timeOrder, true, false, resolution).
+				DeltaEncodedOrder(valueOrder, false, true, resolution)}, nil
+		},
+		decode: func(c *Compressor, d pipelineData) (pipelineData, error) {
+			resolution := c.opts.resolution()
+			return pipelineData{points: d.points.
+				DeltaDecodedOrder(valueOrder, false, true, resolution).
+				DeltaDecodedOrder(timeOrder, true, false, resolution)}, nil
+		},
+	}
+}
+
+// intsStage converts integers to bytes.
+func intsStage(name string, encode func([]uint64) ([]byte, error), decode func([]byte) ([]uint64, error)) stage {
+	return stage{
+		name: name, in: kindInts, out: kindBytes,
+		encode: func(c *Compressor, d pipelineData) (pipelineData, error) {
+			b, err := encode(d.ints)
+			return pipelineData{bytes: b}, err
+		},
+		decode: func(c *Compressor, d pipelineData) (pipelineData, error) {
+			flat, err := decode(d.bytes)
+			return pipelineData{ints: flat}, err
+		},
+	}
+}
+
+type pipelineBackend struct {
+	backend
+	// levels is nil if the backend does not have levels.
+	levels *Levels
+}
+
+var pipelineBackends = map[string]pipelineBackend{
+	"zstd":   {backend{(*Compressor).compressZstd, (*Compressor).decompressZstd}, &zstdLevels},
+	"gzip":   {backend{(*Compressor).compressGzip, (*Compressor).decompressGzip}, &flateLevels},
+	"zlib":   {backend{(*Compressor).compressZlib, (*Compressor).decompressZlib}, &flateLevels},
+	"brotli": {backend{(*Compressor).compressBrotli, (*Compressor).decompressBrotli}, &brotliLevels},
+	"lzfse":  {backend{(*Compressor).compressLzfse, (*Compressor).decompressLzfseBytes}, nil},
+	"lzma":   {backend{(*Compressor).compressLzma, (*Compressor).decompressLzma}, &lzmaLevels},
+	"lz4":    {backend{(*Compressor).compressLz4, (*Compressor).decompressLz4}, nil},
+	"snappy": {backend{(*Compressor).compressSnappy, (*Compressor).decompressSnappy}, nil},
+}
+
+// backendStage compresses bytes with b at level, or its default if nil.
+func backendStage(name string, b pipelineBackend, level *int) stage {
+	// The backends read their level from the Compressor's Options.
+	withLevel := func(c *Compressor) *Compressor {
+		opts := c.opts
+		opts.Level = level
+		return NewCompressorOptions(opts)
+	}
+	return stage{
+		name: name, in: kindBytes, out: kindBytes,
+		encode: func(c *Compressor, d pipelineData) (pipelineData, error) {
+			enc, err := b.compress(withLevel(c), bytes.NewBuffer(d.bytes))
+			return pipelineData{bytes: enc}, err
+		},
+		decode: func(c *Compressor, d pipelineData) (pipelineData, error) {
+			dec, err := b.decompress(withLevel(c), d.bytes)
+			return pipelineData{bytes: dec}, err
+		},
+	}
+}
@@ -0,0 +1,100 @@
+package compress
+
+import (
+	"testing"
+
+	"github.com/smpanaro/time-series-compression/series"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPipeline(t *testing.T) {
+	points, err := series.FromFile("../fixtures/brew2.txt")
+	require.NoError(t, err)
+
+	for _, method := range []Method{
+		"dod|zigzag|simple8b",
+		"delta|csv|zstd:19",
+		"delta | shuffle | zstd",
+		"delta:2|zigzag|bitshuffle|gzip:9",
+		"delta|flatten|varint|brotli:5",
+		"csv|lzma",
+		"fixed|zlib:0",
+	} {
+		for _, interleave := range []bool{false, true} {
+			c := NewCompressorOptions(Options{Method: method, Interleave: interleave, Verify: VerifyOn})
+			enc, err := c.Compress(points)
+			require.NoError(t, err, method)
+
+			// The header is enough to decode.
+			dec, err := Decompress(enc)
+			require.NoError(t, err, method)
+			require.True(t, points.MilliEqual(dec), "%s interleave=%v", method, interleave)
+		}
+	}
+
+	p, err := ParsePipeline("delta | shuffle | zstd")
+	require.NoError(t, err)
+	require.Equal(t, Method("delta|shuffle|zstd"), p.Method())
+}
+
+func TestPipeline_MatchesMethods(t *testing.T) {
+	points, err := series.FromFile("../fixtures/brew3.txt")
+	require.NoError(t, err)
+
+	for pipeline, method := range map[Method]Method{
+		"delta|simple8b":      Simple8b,
+		"dod|zigzag|simple8b": Simple8b,
+		"delta|csv":           CSV,
+		"delta|csv|zstd":      ZstdCSV,
+		"delta|varint|gzip":   GzipVarint,
+		"delta|fastpfor":      FastPFOR,
+		"delta|pfor":          PFORDelta,
+	} {
+		opts := Options{Method: method}
+		if pipeline == "dod|zigzag|simple8b" {
+			opts.TimeDeltaOrder = 2
+		}
+		want, err := NewCompressorOptions(opts).Compress(points)
+		require.NoError(t, err)
+		got, err := NewCompressor(pipeline).Compress(points)
+		require.NoError(t, err)
+
+		_, wantPayload, err := ReadHeader(want)
+		require.NoError(t, err)
+		_, gotPayload, err := ReadHeader(got)
+		require.NoError(t, err)
+		require.Equal(t, wantPayload, gotPayload, pipeline)
+	}
+}
+
+func TestPipeline_Invalid(t *testing.T) {
+	for _, s := range []string{
+		"",
+		"delta|zstd",
+		"delta|zigzag",
+		"csv|zigzag",
+		"delta|csv|zstd:99",
+		"delta|csv|lzfse:1",
+		"delta:0|simple8b",
+		"delta:5|simple8b",
+		"delta:x|simple8b",
+		"dod:2|simple8b",
+		"delta|unknown",
+	} {
+		_, err := ParsePipeline(s)
+		require.Error(t, err, s)
+	}
+
+	points, err := series.FromFile("../fixtures/brew3.txt")
+	require.NoError(t, err)
+	for _, opts := range []Options{
+		{Method: "delta|unknown"},
+		{Method: "delta|csv|zstd", Level: Level(3)},
+		{Method: "delta|csv|zstd", TimeDeltaOrder: 2},
+		{Method: "delta|fixed|zstd", Shuffle: ShuffleByte},
+	} {
+		_, err := NewCompressorOptions(opts).Compress(points)
+		require.Error(t, err, opts.Method)
+	}
+	require.Panics(t, func() { Register(compressorCodec{method: "a|b"}) })
+}
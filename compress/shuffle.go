@@ -0,0 +1,117 @@
+package compress
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+// Shuffle is a Blosc style transform of the fixed-width layout (see the
+// *-fixed methods) applied before the general purpose backend. Delta encoded
+// integers are mostly small, so grouping their bytes or bits by significance
+// turns the high ones into long runs of zeros.
+type Shuffle int
+
+const (
+	// ShuffleNone writes each integer's bytes together.
+	ShuffleNone Shuffle = iota
+	// ShuffleByte writes the first byte of every integer, then the second
+	// byte of every integer, and so on.
+	ShuffleByte
+	// ShuffleBit writes the first bit of every integer, then the second bit
+	// of every integer, and so on.
+	ShuffleBit
+)
+
+var shuffleNames = map[Shuffle]string{
+	ShuffleNone: "none",
+	ShuffleByte: "byte",
+	ShuffleBit:  "bit",
+}
+
+func (s Shuffle) String() string {
+	if name, ok := shuffleNames[s]; ok {
+		return name
+	}
+	return fmt.Sprintf("Shuffle(%d)", int(s))
+}
+
+// ParseShuffle parses one of "none", "byte" or "bit".
+func ParseShuffle(s string) (Shuffle, error) {
+	for shuffle, name := range shuffleNames {
+		if name == s {
+			return shuffle, nil
+		}
+	}
+	return ShuffleNone, fmt.Errorf("invalid shuffle: %s. must be one of: none, byte, bit", s)
+}
+
+// shuffleBytes writes flat as little endian uint64s, grouped by byte.
+func shuffleBytes(flat []uint64) []byte {
+	n := len(flat)
+	b := make([]byte, 8*n)
+	for i, v := range flat {
+		for j := 0; j < 8; j++ {
+			b[j*n+i] = byte(v >> (8 * j))
+		}
+	}
+	return b
+}
+
+func unshuffleBytes(b []byte) ([]uint64, error) {
+	if len(b)%8 != 0 {
+		return nil, fmt.Errorf("invalid byte shuffle length: %d", len(b))
+	}
+	n := len(b) / 8
+	flat := make([]uint64, n)
+	for i := range flat {
+		for j := 0; j < 8; j++ {
+			flat[i] |= uint64(b[j*n+i]) << (8 * j)
+		}
+	}
+	return flat, nil
+}
+
+// shuffleBits writes a uvarint count followed by 64 bit planes, least
+// significant first. Each plane holds one bit of every integer, packed
+// least significant bit first and padded to a whole byte.
+func shuffleBits(flat []uint64) []byte {
+	n := len(flat)
+	planeLen := (n + 7) / 8
+	b := binary.AppendUvarint(make([]byte, 0, binary.MaxVarintLen64+64*planeLen), uint64(n))
+	header := len(b)
+	b = b[:header+64*planeLen]
+	for i, v := range flat {
+		for bit := 0; v != 0; bit, v = bit+1, v>>1 {
+			if v&1 != 0 {
+				b[header+bit*planeLen+i/8] |= 1 << (i % 8)
+			}
+		}
+	}
+	return b
+}
+
+func unshuffleBits(b []byte) ([]uint64, error) {
+	count, read := binary.Uvarint(b)
+	if read <= 0 {
+		return nil, fmt.Errorf("invalid bit shuffle count")
+	}
+	b = b[read:]
+	// Every value takes 64 bits. Check before rounding up to whole planes,
+	// which overflows for a corrupt count.
+	if count > uint64(len(b))*8/64 {
+		return nil, fmt.Errorf("invalid bit shuffle count: %d", count)
+	}
+	planeLen := (count + 7) / 8
+	if uint64(len(b)) != 64*planeLen {
+		return nil, fmt.Errorf("invalid bit shuffle length: %d for %d values", len(b), count)
+	}
+
+	flat := make([]uint64, count)
+	for bit := 0; bit < 64; bit++ {
+		plane := b[uint64(bit)*planeLen:]
+		for i := range flat {
+			flat[i] |= uint64(plane[i/8]>>(i%8)&1) << bit
+		}
+	}
+	return flat, nil
+}
@@ -0,0 +1,76 @@
+package compress
+
+import (
+	"encoding/binary"
+	"math"
+	"testing"
+
+	"github.com/smpanaro/time-series-compression/series"
+	"github.com/stretchr/testify/require"
+)
+
+func TestShuffle_RoundTrip(t *testing.T) {
+	for _, flat := range [][]uint64{
+		{},
+		{1},
+		{0, 1, 2, 3, 4, 5, 6, 7},
+		{1691161006379, 15, 119, 91, 0, math.MaxUint64, 1 << 63, 3, 17},
+	} {
+		b := shuffleBytes(flat)
+		require.Len(t, b, 8*len(flat))
+		dec, err := unshuffleBytes(b)
+		require.NoError(t, err)
+		require.Equal(t, flat, dec)
+
+		dec, err = unshuffleBits(shuffleBits(flat))
+		require.NoError(t, err)
+		require.Equal(t, flat, dec)
+	}
+
+	// The low byte of every value comes first.
+	require.Equal(t, []byte{1, 2, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0}, shuffleBytes([]uint64{1, 2}))
+	// A count of 2, then a plane per bit.
+	bits := shuffleBits([]uint64{1, 3})
+	require.Equal(t, []byte{2, 0b11, 0b10, 0}, bits[:4])
+
+	_, err := unshuffleBytes(make([]byte, 7))
+	require.Error(t, err)
+	_, err = unshuffleBits(bits[:len(bits)-1])
+	require.Error(t, err)
+	// A corrupt count larger than the planes could hold.
+	_, err = unshuffleBits(binary.AppendUvarint(nil, math.MaxUint64))
+	require.Error(t, err)
+	_, err = unshuffleBits(append(binary.AppendUvarint(nil, 1<<61), bits[1:]...))
+	require.Error(t, err)
+}
+
+func TestCompressor_Shuffle(t *testing.T) {
+	points, err := series.FromFile("../fixtures/brew1.txt")
+	require.NoError(t, err)
+
+	sizes := map[Shuffle]int{}
+	for _, shuffle := range []Shuffle{ShuffleNone, ShuffleByte, ShuffleBit} {
+		for _, interleave := range []bool{false, true} {
+			opts := Options{Method: ZstdFixed, Interleave: interleave, Shuffle: shuffle, Verify: VerifyOn}
+			enc, err := NewCompressorOptions(opts).Compress(points)
+			require.NoError(t, err, "shuffle=%s interleave=%v", shuffle, interleave)
+
+			h, _, err := ReadHeader(enc)
+			require.NoError(t, err)
+			require.Equal(t, shuffle, h.Shuffle)
+
+			dec, err := Decompress(enc)
+			require.NoError(t, err)
+			require.True(t, points.MilliEqual(dec))
+			if !interleave {
+				sizes[shuffle] = len(enc)
+			}
+		}
+	}
+	require.Less(t, sizes[ShuffleByte], sizes[ShuffleNone])
+
+	_, err = NewCompressorOptions(Options{Method: ZstdCSV, Shuffle: ShuffleByte}).Compress(points)
+	require.Error(t, err)
+	_, err = NewCompressorOptions(Options{Method: ZstdFixed, Shuffle: Shuffle(3)}).Compress(points)
+	require.Error(t, err)
+}
@@ -0,0 +1,146 @@
+package compress
+
+import (
+	"fmt"
+
+	"github.com/smpanaro/time-series-compression/series"
+)
+
+// timestampEncoder delta-of-delta encodes 64-bit timestamps like Gorilla,
+// without the 32-bit header and delta limits of the original paper. Each
+// delta of delta is zigzag encoded and written with the smallest bucket it
+// fits in. With the default buckets:
+//
+//	0                  '0'
+//	< 2^7              '10'   + 7 bits
+//	< 2^9              '110'  + 9 bits
+//	< 2^12             '1110' + 12 bits
+//	otherwise          '1111' + 64 bits
+//
+// The first timestamp is written in full.
+type timestampEncoder struct {
+	w         *bitWriter
+	buckets   []int
+	started   bool
+	prev      int64
+	prevDelta int64
+}
+
+// DefaultTimestampBuckets are the bucket widths from the Gorilla paper.
+var DefaultTimestampBuckets = []int{7, 9, 12}
+
+const maxTimestampBuckets = 8
+
+func validateTimestampBuckets(buckets []int) error {
+	if len(buckets) > maxTimestampBuckets {
+		return fmt.Errorf("at most %d timestamp buckets are supported", maxTimestampBuckets)
+	}
+	for i, width := range buckets {
+		if width < 1 || width > 63 {
+			return fmt.Errorf("invalid timestamp bucket width: %d", width)
+		}
+		if i > 0 && width <= buckets[i-1] {
+			return fmt.Errorf("timestamp bucket widths must be increasing: %v", buckets)
+		}
+	}
+	return nil
+}
+
+func (e *timestampEncoder) write(t int64) {
+	if !e.started {
+		e.w.writeBits(uint64(t), 64)
+		e.prev = t
+		e.started = true
+		return
+	}
+
+	delta := t - e.prev
+	dod := series.ZigZagEncode64(delta - e.prevDelta)
+	e.prev, e.prevDelta = t, delta
+
+	if dod == 0 {
+		e.w.writeBit(false)
+		return
+	}
+	for _, width := range e.buckets {
+		e.w.writeBit(true)
+		if dod < 1<<width {
+			e.w.writeBit(false)
+			e.w.writeBits(dod, width)
+			return
+		}
+	}
+	e.w.writeBit(true)
+	e.w.writeBits(dod, 64)
+}
+
+func appendTimestampBuckets(b []byte, buckets []int) []byte {
+	b = append(b, byte(len(buckets)))
+	for _, width := range buckets {
+		b = append(b, byte(width))
+	}
+	return b
+}
+
+// readTimestampBuckets reads the bucket widths written by
+// appendTimestampBuckets and returns the rest of b.
+func readTimestampBuckets(b []byte) ([]int, []byte, error) {
+	if len(b) == 0 || len(b) < 1+int(b[0]) {
+		return nil, nil, fmt.Errorf("missing timestamp buckets")
+	}
+	buckets := make([]int, b[0])
+	for i := range buckets {
+		buckets[i] = int(b[1+i])
+	}
+	if err := validateTimestampBuckets(buckets); err != nil {
+		return nil, nil, err
+	}
+	return buckets, b[1+len(buckets):], nil
+}
+
+type timestampDecoder struct {
+	r         *bitReader
+	buckets   []int
+	started   bool
+	prev      int64
+	prevDelta int64
+}
+
+func (d *timestampDecoder) read() (int64, error) {
+	if !d.started {
+		t, err := d.r.readBits(64)
+		if err != nil {
+			return 0, err
+		}
+		d.prev = int64(t)
+		d.started = true
+		return d.prev, nil
+	}
+
+	bit, err := d.r.readBit()
+	if err != nil {
+		return 0, err
+	}
+	var dod uint64
+	if bit {
+		width := 64
+		for _, w := range d.buckets {
+			bit, err := d.r.readBit()
+			if err != nil {
+				return 0, err
+			}
+			if !bit {
+				width = w
+				break
+			}
+		}
+		if dod, err = d.r.readBits(width); err != nil {
+			return 0, err
+		}
+	}
+
+	delta := d.prevDelta + series.ZigZagDecode64(dod)
+	d.prev += delta
+	d.prevDelta = delta
+	return d.prev, nil
+}
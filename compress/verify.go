@@ -0,0 +1,113 @@
+package compress
+
+import (
+	"fmt"
+
+	"github.com/smpanaro/time-series-compression/series"
+)
+
+// VerifyMode controls whether Compress decodes its output and checks it
+// against the input. The zero value is VerifyOn.
+type VerifyMode int
+
+const (
+	// VerifyOn requires times to match at Options.Resolution and values to
+	// match at Options.Precision.
+	VerifyOn VerifyMode = iota
+	// VerifyOff skips verification.
+	VerifyOff
+	// VerifyStrict requires times and values to match exactly.
+	VerifyStrict
+)
+
+var verifyModeNames = map[VerifyMode]string{
+	VerifyOff:    "off",
+	VerifyOn:     "on",
+	VerifyStrict: "strict",
+}
+
+func (m VerifyMode) String() string {
+	if name, ok := verifyModeNames[m]; ok {
+		return name
+	}
+	return fmt.Sprintf("VerifyMode(%d)", int(m))
+}
+
+// ParseVerifyMode parses one of "off", "on" or "strict".
+func ParseVerifyMode(s string) (VerifyMode, error) {
+	for mode, name := range verifyModeNames {
+		if name == s {
+			return mode, nil
+		}
+	}
+	return VerifyOn, fmt.Errorf("invalid verify mode: %s. must be one of: off, on, strict", s)
+}
+
+// MismatchError describes the first point that did not survive a round trip.
+// Expected or Actual is nil when the decoded series has a different length.
+// Their times are printed in Resolution units.
+type MismatchError struct {
+	Method     Method
+	Mode       VerifyMode
+	Resolution series.Resolution
+	Index      int
+	Expected   *series.Point
+	Actual     *series.Point
+}
+
+func (e *MismatchError) Error() string {
+	return fmt.Sprintf("%s: decoded point %d does not match original (verify=%s): expected %s, got %s",
+		e.Method, e.Index, e.Mode, e.formatPoint(e.Expected), e.formatPoint(e.Actual))
+}
+
+func (e *MismatchError) formatPoint(p *series.Point) string {
+	if p == nil {
+		return "no point"
+	}
+	return fmt.Sprintf("{%d%s %v}", p.TimeUnix(e.Resolution), e.Resolution, p.Value)
+}
+
+// verify decodes payload and compares it to points according to c.opts.Verify.
+func (c *Compressor) verify(codec Codec, points series.Points, payload []byte) error {
+	if c.opts.Verify == VerifyOff {
+		return nil
+	}
+
+	decoded, err := codec.Decode(payload, c.opts)
+	if err != nil {
+		return fmt.Errorf("%s: verifying: %w", c.algorithm, err)
+	}
+	return Verify(c.opts, points, decoded)
+}
+
+// Verify compares decoded points produced by opts.Method to the original
+// points according to opts.Verify, opts.Resolution and opts.Precision. It
+// returns a *MismatchError describing the first difference.
+func Verify(opts Options, original, decoded series.Points) error {
+	mode, method := opts.Verify, opts.Method
+	var equal func(a, b *series.Point) bool
+	switch mode {
+	case VerifyOff:
+		return nil
+	case VerifyOn:
+		resolution, precision := opts.resolution(), opts.precision()
+		equal = func(a, b *series.Point) bool { return a.ScaledEqual(b, resolution, precision) }
+	case VerifyStrict:
+		equal = (*series.Point).Equal
+	default:
+		return fmt.Errorf("invalid verify mode: %s", mode)
+	}
+
+	i := original.Mismatch(decoded, equal)
+	if i < 0 {
+		return nil
+	}
+	mismatch := &MismatchError{Method: method, Mode: mode, Resolution: opts.resolution(), Index: i}
+	if i < len(original) {
+		mismatch.Expected = original[i]
+	}
+	if i < len(decoded) {
+		mismatch.Actual = decoded[i]
+	}
+	return mismatch
+}
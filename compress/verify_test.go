@@ -0,0 +1,71 @@
+package compress
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/smpanaro/time-series-compression/series"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompressor_Verify(t *testing.T) {
+	points, err := series.FromFile("../fixtures/brew2.txt")
+	require.NoError(t, err)
+
+	_, err = NewCompressorOptions(Options{Method: CSV, Verify: VerifyOn}).Compress(points)
+	require.NoError(t, err)
+
+	// Delta decoding accumulates float error, so CSV is only milli-exact.
+	_, err = NewCompressorOptions(Options{Method: CSV, Verify: VerifyStrict}).Compress(points)
+	var mismatch *MismatchError
+	require.True(t, errors.As(err, &mismatch))
+	require.Equal(t, CSV, mismatch.Method)
+	require.Equal(t, points[mismatch.Index], mismatch.Expected)
+	require.True(t, mismatch.Expected.MilliEqual(mismatch.Actual))
+	require.False(t, mismatch.Expected.Equal(mismatch.Actual))
+
+	// Times are reported in the resolution they were compared at.
+	_, err = NewCompressorOptions(Options{Method: CSV, Resolution: series.Microsecond, Verify: VerifyStrict}).Compress(points)
+	require.True(t, errors.As(err, &mismatch))
+	require.Contains(t, err.Error(), fmt.Sprintf("{%dus ", mismatch.Expected.TimeUnix(series.Microsecond)))
+}
+
+// truncatingCodec loses the last point.
+type truncatingCodec struct{ reverseCodec }
+
+func (truncatingCodec) Method() Method { return Method("truncating-csv") }
+
+func (c truncatingCodec) Decode(b []byte, opts Options) (series.Points, error) {
+	points, err := c.reverseCodec.Decode(b, opts)
+	if err != nil {
+		return nil, err
+	}
+	return points[:len(points)-1], nil
+}
+
+func TestCompressor_VerifyDefault(t *testing.T) {
+	Register(truncatingCodec{})
+	t.Cleanup(func() { unregister(Method("truncating-csv")) })
+	points, err := series.FromFile("../fixtures/brew2.txt")
+	require.NoError(t, err)
+
+	// The zero Options verify the output.
+	_, err = NewCompressor(Method("truncating-csv")).Compress(points)
+	var mismatch *MismatchError
+	require.True(t, errors.As(err, &mismatch))
+	require.Nil(t, mismatch.Actual)
+
+	_, err = NewCompressorOptions(Options{Method: Method("truncating-csv"), Verify: VerifyOff}).Compress(points)
+	require.NoError(t, err)
+}
+
+func TestParseVerifyMode(t *testing.T) {
+	for _, mode := range []VerifyMode{VerifyOff, VerifyOn, VerifyStrict} {
+		parsed, err := ParseVerifyMode(mode.String())
+		require.NoError(t, err)
+		require.Equal(t, mode, parsed)
+	}
+	_, err := ParseVerifyMode("sometimes")
+	require.Error(t, err)
+}
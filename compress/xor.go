@@ -0,0 +1,77 @@
+package compress
+
+import (
+	"encoding/binary"
+	"fmt"
+
+	"github.com/smpanaro/time-series-compression/series"
+)
+
+// floatEncoder and floatDecoder are implemented by the XOR value encoders
+// (Gorilla and Chimp).
+type floatEncoder interface {
+	write(v float64)
+}
+
+type floatDecoder interface {
+	read() (float64, error)
+}
+
+func (c *Compressor) timestampBuckets() []int {
+	if c.opts.TimestampBuckets == nil {
+		return DefaultTimestampBuckets
+	}
+	return c.opts.TimestampBuckets
+}
+
+// compressXOR writes the point count and timestamp bucket widths followed by
+// each point's delta-of-delta timestamp and XOR encoded value.
+func (c *Compressor) compressXOR(points series.Points, newEncoder func(*bitWriter) floatEncoder) ([]byte, error) {
+	buckets := c.timestampBuckets()
+	if err := validateTimestampBuckets(buckets); err != nil {
+		return nil, err
+	}
+
+	buf := binary.AppendUvarint(nil, uint64(len(points)))
+	w := &bitWriter{buf: appendTimestampBuckets(buf, buckets)}
+
+	times := &timestampEncoder{w: w, buckets: buckets}
+	values := newEncoder(w)
+	for _, pt := range points {
+		times.write(pt.TimeUnix(c.opts.resolution()))
+		values.write(pt.Value)
+	}
+	return w.bytes(), nil
+}
+
+func (c *Compressor) decompressXOR(b []byte, newDecoder func(*bitReader) floatDecoder) (series.Points, error) {
+	count, n := binary.Uvarint(b)
+	if n <= 0 {
+		return nil, fmt.Errorf("invalid point count")
+	}
+	buckets, b, err := readTimestampBuckets(b[n:])
+	if err != nil {
+		return nil, err
+	}
+
+	r := &bitReader{buf: b}
+	if count > uint64(len(r.buf))*8 {
+		return nil, fmt.Errorf("invalid point count: %d", count)
+	}
+
+	times := &timestampDecoder{r: r, buckets: buckets}
+	values := newDecoder(r)
+	points := make(series.Points, count)
+	for i := range points {
+		t, err := times.read()
+		if err != nil {
+			return nil, err
+		}
+		v, err := values.read()
+		if err != nil {
+			return nil, err
+		}
+		points[i] = &series.Point{Time: c.opts.resolution().Time(t), Value: v}
+	}
+	return points, nil
+}
@@ -0,0 +1,99 @@
+package compress
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/smpanaro/time-series-compression/series"
+	"github.com/stretchr/testify/require"
+)
+
+func TestXOREncoders(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	values := []float64{0, 0, 1, -1, math.Inf(1), math.NaN(), math.MaxFloat64, math.SmallestNonzeroFloat64}
+	v := 20.0
+	for i := 0; i < 1000; i++ {
+		v += math.Round(rng.NormFloat64()*100) / 100
+		values = append(values, v)
+		if i%7 == 0 {
+			values = append(values, values[rng.Intn(len(values))])
+		}
+	}
+
+	for name, codec := range map[string]struct {
+		newEncoder func(*bitWriter) floatEncoder
+		newDecoder func(*bitReader) floatDecoder
+	}{
+		"gorilla": {
+			func(w *bitWriter) floatEncoder { return newGorillaEncoder(w) },
+			func(r *bitReader) floatDecoder { return newGorillaDecoder(r) },
+		},
+		"chimp": {
+			func(w *bitWriter) floatEncoder { return newChimpEncoder(w, 1) },
+			func(r *bitReader) floatDecoder { return newChimpDecoder(r, 1) },
+		},
+		"chimp128": {
+			func(w *bitWriter) floatEncoder { return newChimpEncoder(w, chimp128Window) },
+			func(r *bitReader) floatDecoder { return newChimpDecoder(r, chimp128Window) },
+		},
+	} {
+		w := &bitWriter{}
+		enc := codec.newEncoder(w)
+		for _, v := range values {
+			enc.write(v)
+		}
+
+		dec := codec.newDecoder(&bitReader{buf: w.bytes()})
+		for i, v := range values {
+			got, err := dec.read()
+			require.NoError(t, err)
+			require.Equal(t, math.Float64bits(v), math.Float64bits(got), "%s index %d", name, i)
+		}
+	}
+}
+
+func TestTimestampEncoder(t *testing.T) {
+	// Regular samples, jitter, out of order samples and gaps longer than 2^31 ms.
+	times := []int64{1691161006379, 1691161006394, 1691161006513, 1691161006604, 1691161006600, 1691161006700}
+	times = append(times, times[len(times)-1]+1<<33, math.MaxInt64, math.MinInt64, 0)
+
+	for _, buckets := range [][]int{DefaultTimestampBuckets, nil, {1, 2, 3, 4, 5, 6, 7, 63}} {
+		w := &bitWriter{}
+		enc := &timestampEncoder{w: w, buckets: buckets}
+		for _, ts := range times {
+			enc.write(ts)
+		}
+
+		dec := &timestampDecoder{r: &bitReader{buf: w.bytes()}, buckets: buckets}
+		for _, ts := range times {
+			got, err := dec.read()
+			require.NoError(t, err)
+			require.Equal(t, ts, got, "buckets %v", buckets)
+		}
+	}
+}
+
+func TestCompressor_XOR(t *testing.T) {
+	points := series.Points{
+		{Time: time.UnixMilli(0), Value: 17.52},
+		{Time: time.UnixMilli(1 << 40), Value: -3.25},
+		{Time: time.UnixMilli(1<<40 + 15), Value: -3.25},
+	}
+	for _, method := range []Method{Gorilla, Chimp, Chimp128} {
+		for _, buckets := range [][]int{nil, {4, 16, 32}} {
+			c := NewCompressorOptions(Options{Method: method, TimestampBuckets: buckets, Verify: VerifyStrict})
+			enc, err := c.Compress(points)
+			require.NoError(t, err)
+
+			// Bucket widths are stored in the payload.
+			dec, err := NewCompressor(method).Decompress(enc)
+			require.NoError(t, err)
+			require.True(t, points.Equal(dec))
+		}
+
+		_, err := NewCompressorOptions(Options{Method: method, TimestampBuckets: []int{9, 7}}).Compress(points)
+		require.Error(t, err)
+	}
+}
@@ -0,0 +1,113 @@
+package evaluate
+
+import (
+	"runtime"
+	"sort"
+	"time"
+)
+
+// Benchmark controls how encoding and decoding are timed.
+type Benchmark struct {
+	// Warmup iterations run before measuring and are discarded.
+	Warmup int
+	// Iterations is the number of measured iterations. When Duration is set
+	// it is the minimum number of iterations.
+	Iterations int
+	// Duration, if set, picks the number of iterations so that measuring
+	// takes roughly this long, like the iOS app's Runner.measure.
+	Duration time.Duration
+}
+
+// SingleRun times one encode and one decode.
+var SingleRun = Benchmark{Iterations: 1}
+
+// maxIterations matches the iOS app's Runner.
+const maxIterations = 50_000
+
+// Timing summarizes the measured iterations of one operation.
+type Timing struct {
+	Iterations int
+	Median     time.Duration
+	P95        time.Duration
+	// AllocsPerOp and BytesPerOp are heap allocations per iteration.
+	AllocsPerOp uint64
+	BytesPerOp  uint64
+}
+
+// PointsPerSecond is the median throughput for n points.
+func (t Timing) PointsPerSecond(n int) float64 {
+	if t.Median <= 0 {
+		return 0
+	}
+	return float64(n) / t.Median.Seconds()
+}
+
+// MBPerSecond is the median throughput for size bytes.
+func (t Timing) MBPerSecond(size int64) float64 {
+	if t.Median <= 0 {
+		return 0
+	}
+	return float64(size) / 1e6 / t.Median.Seconds()
+}
+
+// add sums other into t. The median and p95 become totals, not percentiles
+// of the combined iterations.
+func (t *Timing) add(other Timing) {
+	t.Iterations += other.Iterations
+	t.Median += other.Median
+	t.P95 += other.P95
+	t.AllocsPerOp += other.AllocsPerOp
+	t.BytesPerOp += other.BytesPerOp
+}
+
+// measure runs fn according to b and times each iteration.
+func (b Benchmark) measure(fn func() error) (Timing, error) {
+	for i := 0; i < b.Warmup; i++ {
+		if err := fn(); err != nil {
+			return Timing{}, err
+		}
+	}
+
+	iterations := b.Iterations
+	if iterations < 1 {
+		iterations = 1
+	}
+	if b.Duration > 0 {
+		// Get a rough idea of how long an iteration takes so we can choose a reasonable iteration count.
+		start := time.Now()
+		if err := fn(); err != nil {
+			return Timing{}, err
+		}
+		approx := time.Since(start)
+		if approx <= 0 {
+			approx = time.Nanosecond
+		}
+		if n := int(b.Duration / approx); n > iterations {
+			iterations = n
+		}
+		if iterations > maxIterations {
+			iterations = maxIterations
+		}
+	}
+
+	durations := make([]time.Duration, iterations)
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	for i := range durations {
+		start := time.Now()
+		if err := fn(); err != nil {
+			return Timing{}, err
+		}
+		durations[i] = time.Since(start)
+	}
+	runtime.ReadMemStats(&after)
+
+	sort.Slice(durations, func(i, j int) bool { return durations[i] < durations[j] })
+	return Timing{
+		Iterations:  iterations,
+		Median:      durations[len(durations)/2],
+		P95:         durations[(len(durations)*95-1)/100],
+		AllocsPerOp: (after.Mallocs - before.Mallocs) / uint64(iterations),
+		BytesPerOp:  (after.TotalAlloc - before.TotalAlloc) / uint64(iterations),
+	}, nil
+}
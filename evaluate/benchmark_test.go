@@ -0,0 +1,32 @@
+package evaluate
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestBenchmark_measure(t *testing.T) {
+	calls := 0
+	timing, err := Benchmark{Warmup: 2, Iterations: 20}.measure(func() error {
+		calls++
+		time.Sleep(time.Duration(calls) * 10 * time.Microsecond)
+		return nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, 22, calls)
+	require.Equal(t, 20, timing.Iterations)
+	require.LessOrEqual(t, timing.Median, timing.P95)
+	require.Greater(t, timing.PointsPerSecond(100), 0.0)
+
+	calls = 0
+	timing, err = Benchmark{Iterations: 1, Duration: 5 * time.Millisecond}.measure(func() error {
+		calls++
+		time.Sleep(100 * time.Microsecond)
+		return nil
+	})
+	require.NoError(t, err)
+	require.Greater(t, timing.Iterations, 1)
+	require.Equal(t, timing.Iterations+1, calls)
+}
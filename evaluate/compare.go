@@ -0,0 +1,187 @@
+package evaluate
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/smpanaro/time-series-compression/compress"
+)
+
+// Comparison is the combined result of one method and layout over every file.
+// Sizes, timings and allocations are summed across files, so Encode.Median is
+// the total of each file's median, not the median file.
+type Comparison struct {
+	Result
+	Files []string
+	Err   error
+}
+
+type Comparisons []Comparison
+
+// Compare evaluates each method with both split and interleaved layouts over
+// every file. Every option other than Method and Interleave is taken from
+// opts. Methods that fail are reported with Err set rather than stopping the
+// comparison.
+func Compare(methods compress.Methods, paths []string, opts compress.Options, benchmark Benchmark) (Comparisons, error) {
+	var comparisons Comparisons
+	for _, method := range methods {
+		for _, interleave := range []bool{false, true} {
+			opts.Method, opts.Interleave = method, interleave
+			comparison, err := compareFiles(opts, paths, benchmark)
+			if err != nil {
+				return nil, err
+			}
+			comparisons = append(comparisons, comparison)
+		}
+	}
+
+	comparisons.Sort()
+	return comparisons, nil
+}
+
+// SweepLevels evaluates opts.Method at every level it supports over every
+// file, in level order. Levels that fail are reported with Err set.
+func SweepLevels(paths []string, opts compress.Options, benchmark Benchmark) (Comparisons, error) {
+	if _, ok := compress.MethodLevels(opts.Method); !ok {
+		return nil, fmt.Errorf("%s does not support compression levels", opts.Method)
+	}
+
+	return sweep(compress.Methods{opts.Method}, []bool{opts.Interleave}, paths, opts, benchmark)
+}
+
+// Sweep evaluates each method at every level it supports, or once at its
+// default if it has none, with both split and interleaved layouts over every
+// file. Every option other than Method, Interleave and Level is taken from
+// opts. Comparisons are sorted like Compare.
+func Sweep(methods compress.Methods, paths []string, opts compress.Options, benchmark Benchmark) (Comparisons, error) {
+	comparisons, err := sweep(methods, []bool{false, true}, paths, opts, benchmark)
+	if err != nil {
+		return nil, err
+	}
+	comparisons.Sort()
+	return comparisons, nil
+}
+
+// sweep evaluates each method at every level it supports with each layout,
+// in method then level order.
+func sweep(methods compress.Methods, layouts []bool, paths []string, opts compress.Options, benchmark Benchmark) (Comparisons, error) {
+	var comparisons Comparisons
+	for _, method := range methods {
+		levels := []*int{nil}
+		if l, ok := compress.MethodLevels(method); ok {
+			levels = levels[:0]
+			for _, level := range l.All() {
+				levels = append(levels, compress.Level(level))
+			}
+		}
+
+		for _, level := range levels {
+			for _, interleave := range layouts {
+				opts.Method, opts.Level, opts.Interleave = method, level, interleave
+				comparison, err := compareFiles(opts, paths, benchmark)
+				if err != nil {
+					return nil, err
+				}
+				comparisons = append(comparisons, comparison)
+			}
+		}
+	}
+	return comparisons, nil
+}
+
+// Frontier returns the Pareto-optimal comparisons: those that no other
+// comparison beats on ratio, total encode time and total decode time at once.
+// Failed comparisons are dropped. Order is preserved.
+func (c Comparisons) Frontier() Comparisons {
+	var frontier Comparisons
+	for i, comparison := range c {
+		if comparison.Err != nil {
+			continue
+		}
+		dominated := false
+		for j, other := range c {
+			if i != j && other.Err == nil && other.dominates(comparison) {
+				dominated = true
+				break
+			}
+		}
+		if !dominated {
+			frontier = append(frontier, comparison)
+		}
+	}
+	return frontier
+}
+
+// dominates reports whether c is at least as good as other on ratio, total
+// encode time and total decode time, and strictly better on one of them.
+func (c Comparison) dominates(other Comparison) bool {
+	ratio, otherRatio := c.Ratio(), other.Ratio()
+	if ratio < otherRatio || c.Encode.Median > other.Encode.Median || c.Decode.Median > other.Decode.Median {
+		return false
+	}
+	return ratio > otherRatio || c.Encode.Median < other.Encode.Median || c.Decode.Median < other.Decode.Median
+}
+
+// compareFiles evaluates opts over every file. It only returns an error if a
+// file cannot be read.
+func compareFiles(opts compress.Options, paths []string, benchmark Benchmark) (Comparison, error) {
+	comparison := Comparison{Result: Result{Algorithm: opts.Method, Level: opts.Level, Interleave: opts.Interleave, Verify: opts.Verify}}
+	for _, path := range paths {
+		evaluation, err := NewEvaluation(opts, path)
+		if err != nil {
+			return Comparison{}, err
+		}
+		evaluation.Benchmark = benchmark
+		result, err := evaluation.Run()
+		if err != nil {
+			comparison.Err = err
+			break
+		}
+		comparison.add(result)
+	}
+	return comparison, nil
+}
+
+func (c *Comparison) add(r Result) {
+	c.Files = append(c.Files, r.File)
+	c.NumPoints += r.NumPoints
+	c.Size += r.Size
+	c.Encode.add(r.Encode)
+	c.Decode.add(r.Decode)
+}
+
+// Sort orders comparisons by compressed size, smallest first. Failed
+// comparisons are last.
+func (c Comparisons) Sort() {
+	sort.SliceStable(c, func(i, j int) bool {
+		if (c[i].Err == nil) != (c[j].Err == nil) {
+			return c[i].Err == nil
+		}
+		return c[i].Size < c[j].Size
+	})
+}
+
+func (c Comparisons) PrintTable(w io.Writer) error {
+	return Write(w, FormatText, c.Records())
+}
+
+// Record flattens the comparison. File lists every file, comma separated.
+func (c Comparison) Record() Record {
+	record := c.Result.Record()
+	record.File = strings.Join(c.Files, ",")
+	record.Total = true
+	if c.Err != nil {
+		record.Error = c.Err.Error()
+	}
+	return record
+}
+
+func (c Comparisons) Records() []Record {
+	records := make([]Record, len(c))
+	for i, comparison := range c {
+		records[i] = comparison.Record()
+	}
+	return records
+}
@@ -0,0 +1,113 @@
+package evaluate
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/smpanaro/time-series-compression/compress"
+	"github.com/smpanaro/time-series-compression/series"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompare(t *testing.T) {
+	// The pipeline fails to parse.
+	methods := compress.Methods{compress.CSV, compress.Simple8b, compress.BP32, "delta|unknown"}
+	paths := []string{"../fixtures/brew2.txt", "../fixtures/brew3.txt"}
+
+	comparisons, err := Compare(methods, paths, compress.Options{Verify: compress.VerifyOn}, SingleRun)
+	require.NoError(t, err)
+	require.Len(t, comparisons, 2*len(methods))
+
+	// Smallest first, failures last.
+	require.Equal(t, compress.Simple8b, comparisons[0].Algorithm)
+	require.Equal(t, paths, comparisons[0].Files)
+	numPoints := 0
+	for _, path := range paths {
+		points, err := series.FromFile(path)
+		require.NoError(t, err)
+		numPoints += len(points)
+	}
+	require.Equal(t, numPoints, comparisons[0].NumPoints)
+	for i := 1; i < 6; i++ {
+		require.NoError(t, comparisons[i].Err)
+		require.LessOrEqual(t, comparisons[i-1].Size, comparisons[i].Size)
+	}
+	require.Equal(t, compress.Method("delta|unknown"), comparisons[7].Algorithm)
+	require.Error(t, comparisons[7].Err)
+
+	var buf bytes.Buffer
+	require.NoError(t, comparisons.PrintTable(&buf))
+	require.Contains(t, buf.String(), "interleaved")
+	// Times are summed over the files.
+	require.Contains(t, buf.String(), "Total Encode")
+	require.True(t, comparisons[0].Record().Total)
+}
+
+func TestSweepLevels(t *testing.T) {
+	paths := []string{"../fixtures/brew2.txt", "../fixtures/brew3.txt"}
+
+	opts := compress.Options{Method: compress.GzipCSV, Interleave: true, Verify: compress.VerifyOn}
+	comparisons, err := SweepLevels(paths, opts, SingleRun)
+	require.NoError(t, err)
+	levels, _ := compress.MethodLevels(compress.GzipCSV)
+	require.Len(t, comparisons, len(levels.All()))
+	for i, comparison := range comparisons {
+		require.NoError(t, comparison.Err)
+		require.Equal(t, levels.Min+i, *comparison.Level)
+		require.True(t, comparison.Interleave)
+		require.Equal(t, paths, comparison.Files)
+	}
+	// Level 0 stores without compressing.
+	require.Less(t, comparisons[len(comparisons)-1].Size, comparisons[0].Size)
+
+	_, err = SweepLevels(paths, compress.Options{Method: compress.Simple8b}, SingleRun)
+	require.Error(t, err)
+}
+
+func TestSweep(t *testing.T) {
+	paths := []string{"../fixtures/brew2.txt"}
+	methods := compress.Methods{compress.Simple8b, compress.GzipCSV}
+
+	comparisons, err := Sweep(methods, paths, compress.Options{Verify: compress.VerifyOn}, SingleRun)
+	require.NoError(t, err)
+	levels, _ := compress.MethodLevels(compress.GzipCSV)
+	require.Len(t, comparisons, 2*(1+len(levels.All())))
+	for i, comparison := range comparisons {
+		require.NoError(t, comparison.Err)
+		if comparison.Algorithm == compress.Simple8b {
+			require.Nil(t, comparison.Level)
+		} else {
+			require.NotNil(t, comparison.Level)
+		}
+		if i > 0 {
+			require.LessOrEqual(t, comparisons[i-1].Size, comparison.Size)
+		}
+	}
+
+	frontier := comparisons.Frontier()
+	require.NotEmpty(t, frontier)
+	require.Equal(t, comparisons[0], frontier[0])
+}
+
+func TestComparisons_Frontier(t *testing.T) {
+	comparison := func(size int, encode, decode time.Duration) Comparison {
+		return Comparison{Result: Result{
+			NumPoints: 100,
+			Size:      size,
+			Encode:    Timing{Median: encode},
+			Decode:    Timing{Median: decode},
+		}}
+	}
+	smallest := comparison(100, 9*time.Millisecond, 9*time.Millisecond)
+	fastEncode := comparison(200, time.Millisecond, 5*time.Millisecond)
+	fastDecode := comparison(200, 5*time.Millisecond, time.Millisecond)
+	dominated := comparison(300, 5*time.Millisecond, 5*time.Millisecond)
+	tied := comparison(200, time.Millisecond, 5*time.Millisecond)
+	failed := comparison(1, 0, 0)
+	failed.Err = errors.New("failed")
+
+	comparisons := Comparisons{smallest, fastEncode, fastDecode, dominated, tied, failed}
+	require.Equal(t, Comparisons{smallest, fastEncode, fastDecode, tied}, comparisons.Frontier())
+}
@@ -0,0 +1,218 @@
+package evaluate
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+type Format string
+
+const (
+	FormatText     Format = "text"
+	FormatJSON     Format = "json"
+	FormatCSV      Format = "csv"
+	FormatMarkdown Format = "markdown"
+)
+
+var AllFormats = []Format{FormatText, FormatJSON, FormatCSV, FormatMarkdown}
+
+func ParseFormat(s string) (Format, error) {
+	for _, f := range AllFormats {
+		if string(f) == s {
+			return f, nil
+		}
+	}
+	return "", fmt.Errorf("invalid format: %s. must be one of: %v", s, AllFormats)
+}
+
+// Record is the serializable form of a Result.
+type Record struct {
+	Method     string  `json:"method"`
+	Level      *int    `json:"level,omitempty"`
+	Interleave bool    `json:"interleave"`
+	Verify     string  `json:"verify"`
+	File       string  `json:"file"`
+	NumPoints  int     `json:"points"`
+	NaiveSize  int64   `json:"naive_size"`
+	Size       int     `json:"size"`
+	Ratio      float64 `json:"ratio"`
+
+	// Total is set for a Comparison, whose File lists every file. Times are
+	// then the sum of each file's median or p95, the time to process every
+	// file once, rather than a median over the files.
+	Total bool `json:"total,omitempty"`
+
+	EncodeIterations int           `json:"encode_iterations"`
+	EncodeTime       time.Duration `json:"encode_ns"`
+	EncodeP95        time.Duration `json:"encode_p95_ns"`
+	EncodeAllocs     uint64        `json:"encode_allocs"`
+	EncodeBytes      uint64        `json:"encode_alloc_bytes"`
+	EncodePointsPerS float64       `json:"encode_points_per_sec"`
+	EncodeMBPerS     float64       `json:"encode_mb_per_sec"`
+
+	DecodeIterations int           `json:"decode_iterations"`
+	DecodeTime       time.Duration `json:"decode_ns"`
+	DecodeP95        time.Duration `json:"decode_p95_ns"`
+	DecodeAllocs     uint64        `json:"decode_allocs"`
+	DecodeBytes      uint64        `json:"decode_alloc_bytes"`
+	DecodePointsPerS float64       `json:"decode_points_per_sec"`
+	DecodeMBPerS     float64       `json:"decode_mb_per_sec"`
+
+	Error string `json:"error,omitempty"`
+}
+
+var recordHeader = []string{
+	"method", "level", "interleave", "verify", "file", "points", "naive_size", "size", "ratio", "total",
+	"encode_iterations", "encode_ns", "encode_p95_ns", "encode_allocs", "encode_alloc_bytes", "encode_points_per_sec", "encode_mb_per_sec",
+	"decode_iterations", "decode_ns", "decode_p95_ns", "decode_allocs", "decode_alloc_bytes", "decode_points_per_sec", "decode_mb_per_sec",
+	"error",
+}
+
+func (r Record) strings() []string {
+	return []string{
+		r.Method,
+		r.level(),
+		strconv.FormatBool(r.Interleave),
+		r.Verify,
+		r.File,
+		strconv.Itoa(r.NumPoints),
+		strconv.FormatInt(r.NaiveSize, 10),
+		strconv.Itoa(r.Size),
+		strconv.FormatFloat(r.Ratio, 'f', 4, 64),
+		strconv.FormatBool(r.Total),
+		strconv.Itoa(r.EncodeIterations),
+		strconv.FormatInt(r.EncodeTime.Nanoseconds(), 10),
+		strconv.FormatInt(r.EncodeP95.Nanoseconds(), 10),
+		strconv.FormatUint(r.EncodeAllocs, 10),
+		strconv.FormatUint(r.EncodeBytes, 10),
+		strconv.FormatFloat(r.EncodePointsPerS, 'f', 0, 64),
+		strconv.FormatFloat(r.EncodeMBPerS, 'f', 4, 64),
+		strconv.Itoa(r.DecodeIterations),
+		strconv.FormatInt(r.DecodeTime.Nanoseconds(), 10),
+		strconv.FormatInt(r.DecodeP95.Nanoseconds(), 10),
+		strconv.FormatUint(r.DecodeAllocs, 10),
+		strconv.FormatUint(r.DecodeBytes, 10),
+		strconv.FormatFloat(r.DecodePointsPerS, 'f', 0, 64),
+		strconv.FormatFloat(r.DecodeMBPerS, 'f', 4, 64),
+		r.Error,
+	}
+}
+
+func (r Record) level() string {
+	if r.Level == nil {
+		return ""
+	}
+	return strconv.Itoa(*r.Level)
+}
+
+// label is the method name, followed by the level if there is one.
+func (r Record) label() string {
+	if r.Level == nil {
+		return r.Method
+	}
+	return r.Method + ":" + r.level()
+}
+
+// markdownLabel is label with the "|" of pipelines escaped for tables.
+func (r Record) markdownLabel() string {
+	return strings.ReplaceAll(r.label(), "|", `\|`)
+}
+
+func (r Record) layout() string {
+	return Result{Interleave: r.Interleave}.Layout()
+}
+
+func (r Record) bitsPerPoint() float64 {
+	return Result{NumPoints: r.NumPoints, Size: r.Size}.BitsPerPoint()
+}
+
+// timeHeader labels a time column, marking it as a total if any record sums
+// its times over several files.
+func timeHeader(records []Record, name string) string {
+	for _, r := range records {
+		if r.Total {
+			return "Total " + name
+		}
+	}
+	return name
+}
+
+// Write writes records to w in the given format.
+func Write(w io.Writer, format Format, records []Record) error {
+	switch format {
+	case FormatText:
+		return writeText(w, records)
+	case FormatJSON:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(records)
+	case FormatCSV:
+		return writeCSV(w, records)
+	case FormatMarkdown:
+		return writeMarkdown(w, records)
+	default:
+		return fmt.Errorf("unsupported format: %s", format)
+	}
+}
+
+func writeText(w io.Writer, records []Record) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintf(tw, "Method\tLayout\tSize\tRatio\tBits/Point\t%s\t%s\tEncode MB/s\tDecode MB/s\t\n",
+		timeHeader(records, "Encode"), timeHeader(records, "Decode"))
+	for _, r := range records {
+		if r.Error != "" {
+			fmt.Fprintf(tw, "%s\t%s\terror\t-\t-\t-\t-\t-\t-\t\n", r.label(), r.layout())
+			continue
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%d\t%.2f\t%.2f\t%v\t%v\t%.2f\t%.2f\t\n",
+			r.label(), r.layout(), r.Size, r.Ratio, r.bitsPerPoint(), r.EncodeTime, r.DecodeTime, r.EncodeMBPerS, r.DecodeMBPerS)
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	for _, r := range records {
+		if r.Error != "" {
+			fmt.Fprintf(w, "%s (%s): %s\n", r.label(), r.layout(), r.Error)
+		}
+	}
+	return nil
+}
+
+func writeCSV(w io.Writer, records []Record) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(recordHeader); err != nil {
+		return err
+	}
+	for _, r := range records {
+		if err := cw.Write(r.strings()); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+func writeMarkdown(w io.Writer, records []Record) error {
+	encode, decode := timeHeader(records, "Encode"), timeHeader(records, "Decode")
+	fmt.Fprintf(w, "| Method | Layout | Points | Size | Ratio | Bits/Point | %s | %s p95 | %s | %s p95 |\n", encode, encode, decode, decode)
+	fmt.Fprintln(w, "|---|---|--:|--:|--:|--:|--:|--:|--:|--:|")
+	for _, r := range records {
+		if r.Error != "" {
+			fmt.Fprintf(w, "| %s | %s | %d | error | - | - | - | - | - | - |\n", r.markdownLabel(), r.layout(), r.NumPoints)
+			continue
+		}
+		_, err := fmt.Fprintf(w, "| %s | %s | %d | %d | %.2f | %.2f | %v | %v | %v | %v |\n",
+			r.markdownLabel(), r.layout(), r.NumPoints, r.Size, r.Ratio, r.bitsPerPoint(), r.EncodeTime, r.EncodeP95, r.DecodeTime, r.DecodeP95)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,101 @@
+package evaluate
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/smpanaro/time-series-compression/compress"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWrite(t *testing.T) {
+	result := Result{
+		Algorithm:  compress.Simple8b,
+		Interleave: true,
+		Verify:     compress.VerifyOn,
+		File:       "brew1.txt",
+		NumPoints:  10,
+		Size:       60,
+		Encode:     Timing{Iterations: 1, Median: 2 * time.Millisecond, P95: 2 * time.Millisecond, AllocsPerOp: 5, BytesPerOp: 100},
+		Decode:     Timing{Iterations: 1, Median: time.Millisecond, P95: time.Millisecond, AllocsPerOp: 3, BytesPerOp: 50},
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, Write(&buf, FormatJSON, []Record{result.Record()}))
+	var records []map[string]any
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &records))
+	require.Equal(t, []map[string]any{{
+		"method":     "simple-8b",
+		"interleave": true,
+		"verify":     "on",
+		"file":       "brew1.txt",
+		"points":     float64(10),
+		"naive_size": float64(120),
+		"size":       float64(60),
+		"ratio":      float64(2),
+
+		"encode_iterations":     float64(1),
+		"encode_ns":             float64(2_000_000),
+		"encode_p95_ns":         float64(2_000_000),
+		"encode_allocs":         float64(5),
+		"encode_alloc_bytes":    float64(100),
+		"encode_points_per_sec": float64(5_000),
+		"encode_mb_per_sec":     float64(0.06),
+
+		"decode_iterations":     float64(1),
+		"decode_ns":             float64(1_000_000),
+		"decode_p95_ns":         float64(1_000_000),
+		"decode_allocs":         float64(3),
+		"decode_alloc_bytes":    float64(50),
+		"decode_points_per_sec": float64(10_000),
+		"decode_mb_per_sec":     float64(0.12),
+	}}, records)
+
+	marshaled, err := json.Marshal(result)
+	require.NoError(t, err)
+	var single map[string]any
+	require.NoError(t, json.Unmarshal(marshaled, &single))
+	require.Equal(t, records[0], single)
+
+	buf.Reset()
+	require.NoError(t, Write(&buf, FormatCSV, []Record{result.Record()}))
+	rows, err := csv.NewReader(&buf).ReadAll()
+	require.NoError(t, err)
+	require.Equal(t, [][]string{
+		recordHeader,
+		{
+			"simple-8b", "", "true", "on", "brew1.txt", "10", "120", "60", "2.0000", "false",
+			"1", "2000000", "2000000", "5", "100", "5000", "0.0600",
+			"1", "1000000", "1000000", "3", "50", "10000", "0.1200",
+			"",
+		},
+	}, rows)
+
+	buf.Reset()
+	require.NoError(t, Write(&buf, FormatMarkdown, []Record{result.Record()}))
+	require.Contains(t, buf.String(), "| simple-8b | interleaved | 10 | 60 | 2.00 | 48.00 | 2ms | 2ms | 1ms | 1ms |")
+
+	result.Algorithm, result.Level = compress.ZstdCSV, compress.Level(19)
+	buf.Reset()
+	require.NoError(t, Write(&buf, FormatText, []Record{result.Record()}))
+	require.Contains(t, buf.String(), "zstd-csv:19")
+	require.Equal(t, 19, *result.Record().Level)
+
+	result.Algorithm, result.Level = "delta|csv|zstd:19", nil
+	buf.Reset()
+	require.NoError(t, Write(&buf, FormatMarkdown, []Record{result.Record()}))
+	require.Contains(t, buf.String(), `| delta\|csv\|zstd:19 | interleaved |`)
+}
+
+func TestParseFormat(t *testing.T) {
+	for _, f := range AllFormats {
+		parsed, err := ParseFormat(string(f))
+		require.NoError(t, err)
+		require.Equal(t, f, parsed)
+	}
+	_, err := ParseFormat("xml")
+	require.Error(t, err)
+}
@@ -1,6 +1,7 @@
 package evaluate
 
 import (
+	"encoding/json"
 	"fmt"
 
 	"github.com/smpanaro/time-series-compression/compress"
@@ -9,50 +10,159 @@ import (
 
 type Evaluation struct {
 	Algorithm  compress.Method
+	Interleave bool
+	Verify     compress.VerifyMode
+	File       string
 	Points     series.Points
 	Compressor *compress.Compressor
+	Benchmark  Benchmark
 }
 
-func NewEvaluation(algorithm compress.Method, interleave bool, dataPath string) (*Evaluation, error) {
-	points, err := series.FromFile(dataPath)
+func NewEvaluation(opts compress.Options, dataPath string) (*Evaluation, error) {
+	resolution := opts.Resolution
+	if resolution == 0 {
+		resolution = series.Millisecond
+	}
+	points, err := series.FromFileResolution(dataPath, resolution)
 	if err != nil {
 		return nil, err
 	}
 
+	// Verify after decoding instead of during Compress so it is not timed.
+	verify := opts.Verify
+	opts.Verify = compress.VerifyOff
+
 	return &Evaluation{
-		Algorithm:  algorithm,
+		Algorithm:  opts.Method,
+		Interleave: opts.Interleave,
+		Verify:     verify,
+		File:       dataPath,
 		Points:     points,
-		Compressor: compress.NewCompressorOptions(compress.Options{Method: algorithm, Interleave: interleave}),
+		Compressor: compress.NewCompressorOptions(opts),
+		Benchmark:  SingleRun,
 	}, nil
 }
 
 func (e *Evaluation) Run() (Result, error) {
-	bytes, err := e.Compressor.Compress(e.Points)
+	var bytes []byte
+	encode, err := e.Benchmark.measure(func() (err error) {
+		bytes, err = e.Compressor.Compress(e.Points)
+		return err
+	})
 	if err != nil {
 		return Result{}, err
 	}
 
+	var decoded series.Points
+	decode, err := e.Benchmark.measure(func() (err error) {
+		decoded, err = e.Compressor.Decompress(bytes)
+		return err
+	})
+	if err != nil {
+		return Result{}, err
+	}
+
+	opts := e.Compressor.Options()
+	opts.Verify = e.Verify
+	if err := compress.Verify(opts, e.Points, decoded); err != nil {
+		return Result{}, err
+	}
+
 	return Result{
-		Algorithm: e.Algorithm,
-		NumPoints: len(e.Points),
-		Size:      len(bytes),
+		Algorithm:  e.Algorithm,
+		Level:      opts.Level,
+		Interleave: e.Interleave,
+		Verify:     e.Verify,
+		File:       e.File,
+		NumPoints:  len(e.Points),
+		Size:       len(bytes),
+		Encode:     encode,
+		Decode:     decode,
 	}, nil
 }
 
 type Result struct {
-	Algorithm compress.Method
-	NumPoints int
-	Size      int
+	Algorithm  compress.Method
+	Level      *int
+	Interleave bool
+	Verify     compress.VerifyMode
+	File       string
+	NumPoints  int
+	Size       int
+	Encode     Timing
+	Decode     Timing
+}
+
+// Layout describes how timestamps and values were arranged before compressing.
+func (r Result) Layout() string {
+	if r.Interleave {
+		return "interleaved"
+	}
+	return "split"
 }
 
 func (r Result) NaiveSize() int64 {
-	// timestamp in int64, value in float32
+	// timestamp in int64, value in float32. Values are float64 in memory, but
+	// the baseline is kept so ratios stay comparable with the iOS app.
 	return int64(r.NumPoints * (8 + 4))
 }
 
+func (r Result) Ratio() float64 {
+	return float64(r.NaiveSize()) / float64(r.Size)
+}
+
+func (r Result) BitsPerPoint() float64 {
+	return float64(r.Size*8) / float64(r.NumPoints)
+}
+
+func (r Result) Record() Record {
+	return Record{
+		Method:     r.Algorithm.String(),
+		Level:      r.Level,
+		Interleave: r.Interleave,
+		Verify:     r.Verify.String(),
+		File:       r.File,
+		NumPoints:  r.NumPoints,
+		NaiveSize:  r.NaiveSize(),
+		Size:       r.Size,
+		Ratio:      r.Ratio(),
+
+		EncodeIterations: r.Encode.Iterations,
+		EncodeTime:       r.Encode.Median,
+		EncodeP95:        r.Encode.P95,
+		EncodeAllocs:     r.Encode.AllocsPerOp,
+		EncodeBytes:      r.Encode.BytesPerOp,
+		EncodePointsPerS: r.Encode.PointsPerSecond(r.NumPoints),
+		EncodeMBPerS:     r.Encode.MBPerSecond(r.NaiveSize()),
+
+		DecodeIterations: r.Decode.Iterations,
+		DecodeTime:       r.Decode.Median,
+		DecodeP95:        r.Decode.P95,
+		DecodeAllocs:     r.Decode.AllocsPerOp,
+		DecodeBytes:      r.Decode.BytesPerOp,
+		DecodePointsPerS: r.Decode.PointsPerSecond(r.NumPoints),
+		DecodeMBPerS:     r.Decode.MBPerSecond(r.NaiveSize()),
+	}
+}
+
+func (r Result) MarshalJSON() ([]byte, error) {
+	return json.Marshal(r.Record())
+}
+
 func (r Result) PrintStats() {
 	fmt.Printf("Algorithm        : %s\n", r.Algorithm)
+	if r.Level != nil {
+		fmt.Printf("Level            : %d\n", *r.Level)
+	}
 	fmt.Printf("Uncompressed     : %v bytes\n", r.NaiveSize())
 	fmt.Printf("Compressed       : %v bytes\n", r.Size)
-	fmt.Printf("Compression Ratio: %.2f\n", float64(r.NaiveSize())/float64(r.Size))
+	fmt.Printf("Compression Ratio: %.2f\n", r.Ratio())
+	r.printTiming("Encode", r.Encode)
+	r.printTiming("Decode", r.Decode)
+}
+
+func (r Result) printTiming(name string, t Timing) {
+	fmt.Printf("%s Time      : %v median, %v p95 (%d iterations)\n", name, t.Median, t.P95, t.Iterations)
+	fmt.Printf("%s Throughput: %.0f points/s, %.2f MB/s\n", name, t.PointsPerSecond(r.NumPoints), t.MBPerSecond(r.NaiveSize()))
+	fmt.Printf("%s Allocs    : %d allocs/op, %d B/op\n", name, t.AllocsPerOp, t.BytesPerOp)
 }
@@ -4,16 +4,18 @@ go 1.20
 
 require (
 	github.com/andybalholm/brotli v1.0.5
+	github.com/blacktop/lzfse-cgo v1.1.19
+	github.com/danielrh/go-xz v0.0.0-20180613074948-15f6c3b7b11f
+	github.com/dataence/encoding v0.0.0-20171223221521-b90e310a0325
 	github.com/jwilder/encoding v0.0.0-20170811194829-b4e1701a28ef
-	github.com/keisku/gorilla v0.3.0
+	github.com/klauspost/compress v1.17.0
+	github.com/pierrec/lz4/v4 v4.1.18
+	github.com/ulikunitz/xz v0.5.11
 	github.com/urfave/cli/v2 v2.25.7
 )
 
 require (
-	github.com/blacktop/lzfse-cgo v1.1.19 // indirect
-	github.com/danielrh/go-xz v0.0.0-20180613074948-15f6c3b7b11f // indirect
 	github.com/dataence/bytebuffer v0.0.0-20131118020616-f1bbd176b4c1 // indirect
-	github.com/dataence/encoding v0.0.0-20171223221521-b90e310a0325 // indirect
 	github.com/davecgh/go-spew v1.1.1 // indirect
 	github.com/pmezard/go-difflib v1.0.0 // indirect
 	gopkg.in/yaml.v3 v3.0.1 // indirect
@@ -2,11 +2,15 @@ package main
 
 import (
 	"fmt"
+	"io"
 	"log"
 	"os"
+	"path/filepath"
+	"strings"
 
 	"github.com/smpanaro/time-series-compression/compress"
 	"github.com/smpanaro/time-series-compression/evaluate"
+	"github.com/smpanaro/time-series-compression/series"
 	"github.com/urfave/cli/v2"
 )
 
@@ -18,44 +22,433 @@ func main() {
 			{
 				Name:  "evaluate",
 				Usage: "[algorithm] [path]",
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:    "method",
+						Aliases: []string{"a", "m"},
+						Usage:   "one of: " + compress.AllMethods.Join(", ") + ", or a pipeline such as delta|csv|zstd:19. required unless --all is set",
+					},
+					&cli.BoolFlag{
+						Name:  "all",
+						Usage: "compare every method in both split and interleaved layouts",
+					},
+					levelFlag,
+					dictFlag,
+					shuffleFlag,
+					&cli.BoolFlag{
+						Name:  "sweep-levels",
+						Usage: "evaluate --method at every compression level it supports",
+					},
+					&cli.BoolFlag{
+						Name:    "interleave",
+						Aliases: []string{"i"},
+						Usage:   "interleave timestamps and values before compressing. typically leads to worse results. does not apply to Gorilla or Chimp. default: false",
+					},
+					&cli.StringSliceFlag{
+						Name:    "path",
+						Aliases: []string{"p"},
+						Usage:   "path to an uncompressed data file. may be repeated with --all or --sweep-levels. required",
+					},
+					verifyFlag,
+					precisionFlag,
+					resolutionFlag,
+					timeDeltaOrderFlag,
+					valueDeltaOrderFlag,
+					formatFlag,
+					warmupFlag,
+					iterationsFlag,
+					benchTimeFlag,
+				},
+				Subcommands: []*cli.Command{
+					{
+						Name:  "sweep",
+						Usage: "run every method at every compression level and report the configurations with the best tradeoff of ratio, encode time and decode time",
+						Flags: []cli.Flag{
+							&cli.StringSliceFlag{
+								Name:    "method",
+								Aliases: []string{"a", "m"},
+								Usage:   "method or pipeline to sweep. may be repeated. default: every method",
+							},
+							&cli.StringSliceFlag{
+								Name:     "path",
+								Aliases:  []string{"p"},
+								Usage:    "path to an uncompressed data file. may be repeated",
+								Required: true,
+							},
+							&cli.BoolFlag{
+								Name:  "all-configs",
+								Usage: "report every configuration, not just the Pareto frontier",
+							},
+							verifyFlag,
+							dictFlag,
+							precisionFlag,
+							resolutionFlag,
+							timeDeltaOrderFlag,
+							valueDeltaOrderFlag,
+							formatFlag,
+							warmupFlag,
+							iterationsFlag,
+							benchTimeFlag,
+						},
+						Action: func(c *cli.Context) error {
+							verify, err := compress.ParseVerifyMode(c.String("verify"))
+							if err != nil {
+								return err
+							}
+							resolution, err := series.ParseResolution(c.String("resolution"))
+							if err != nil {
+								return err
+							}
+							format, err := evaluate.ParseFormat(c.String("format"))
+							if err != nil {
+								return err
+							}
+
+							methods := compress.AllMethods
+							if c.IsSet("method") {
+								methods = nil
+								for _, name := range c.StringSlice("method") {
+									method, err := parseMethod(name)
+									if err != nil {
+										return err
+									}
+									methods = append(methods, method)
+								}
+							}
+
+							dict, err := dictionary(c)
+							if err != nil {
+								return err
+							}
+
+							opts := compress.Options{
+								Verify:          verify,
+								Resolution:      resolution,
+								Precision:       c.Int64("precision"),
+								TimeDeltaOrder:  c.Int("time-delta-order"),
+								ValueDeltaOrder: c.Int("value-delta-order"),
+								Dictionary:      dict,
+							}
+							comparisons, err := evaluate.Sweep(methods, c.StringSlice("path"), opts, benchmark(c))
+							if err != nil {
+								return err
+							}
+							if !c.Bool("all-configs") {
+								comparisons = comparisons.Frontier()
+							}
+							return evaluate.Write(os.Stdout, format, comparisons.Records())
+						},
+					},
+				},
+				Action: func(c *cli.Context) error {
+					verify, err := compress.ParseVerifyMode(c.String("verify"))
+					if err != nil {
+						return err
+					}
+					resolution, err := series.ParseResolution(c.String("resolution"))
+					if err != nil {
+						return err
+					}
+					format, err := evaluate.ParseFormat(c.String("format"))
+					if err != nil {
+						return err
+					}
+					dict, err := dictionary(c)
+					if err != nil {
+						return err
+					}
+					shuffle, err := compress.ParseShuffle(c.String("shuffle"))
+					if err != nil {
+						return err
+					}
+					benchmark := benchmark(c)
+
+					// Not marked Required, which would also apply to the sweep subcommand.
+					paths := c.StringSlice("path")
+					if len(paths) == 0 {
+						return fmt.Errorf("--path is required")
+					}
+
+					if c.Bool("all") {
+						if c.IsSet("level") || c.IsSet("shuffle") {
+							return fmt.Errorf("--level and --shuffle cannot be used with --all")
+						}
+						opts := compress.Options{
+							Verify:          verify,
+							Resolution:      resolution,
+							Precision:       c.Int64("precision"),
+							TimeDeltaOrder:  c.Int("time-delta-order"),
+							ValueDeltaOrder: c.Int("value-delta-order"),
+							Dictionary:      dict,
+						}
+						comparisons, err := evaluate.Compare(compress.AllMethods, paths, opts, benchmark)
+						if err != nil {
+							return err
+						}
+						return evaluate.Write(os.Stdout, format, comparisons.Records())
+					}
+
+					algorithm, err := parseMethod(c.String("method"))
+					if err != nil {
+						return err
+					}
+
+					opts := compress.Options{
+						Method:          algorithm,
+						Interleave:      c.Bool("interleave"),
+						Verify:          verify,
+						Resolution:      resolution,
+						Precision:       c.Int64("precision"),
+						TimeDeltaOrder:  c.Int("time-delta-order"),
+						ValueDeltaOrder: c.Int("value-delta-order"),
+						Level:           level(c),
+						Dictionary:      dict,
+						Shuffle:         shuffle,
+					}
+
+					if c.Bool("sweep-levels") {
+						comparisons, err := evaluate.SweepLevels(paths, opts, benchmark)
+						if err != nil {
+							return err
+						}
+						return evaluate.Write(os.Stdout, format, comparisons.Records())
+					}
+
+					if len(paths) != 1 {
+						return fmt.Errorf("exactly one path is required without --all or --sweep-levels")
+					}
+					evaluation, err := evaluate.NewEvaluation(opts, paths[0])
+					if err != nil {
+						return err
+					}
+					evaluation.Benchmark = benchmark
+
+					result, err := evaluation.Run()
+					if err != nil {
+						return err
+					}
+
+					if format != evaluate.FormatText {
+						return evaluate.Write(os.Stdout, format, []evaluate.Record{result.Record()})
+					}
+					result.PrintStats()
+
+					return nil
+				},
+			},
+			{
+				Name:  "compress",
+				Usage: "compress a data file",
 				Flags: []cli.Flag{
 					&cli.StringFlag{
 						Name:     "method",
 						Aliases:  []string{"a", "m"},
-						Usage:    "one of: " + compress.AllMethods.Join(", "),
+						Usage:    "one of: " + compress.AllMethods.Join(", ") + ", or a pipeline such as delta|csv|zstd:19",
 						Required: true,
 					},
 					&cli.BoolFlag{
 						Name:    "interleave",
 						Aliases: []string{"i"},
-						Usage:   "interleave timestamps and values before compressing. typically leads to worse results. does not apply to Gorilla. default: false",
+						Usage:   "interleave timestamps and values before compressing. default: false",
 					},
+					levelFlag,
+					dictFlag,
+					shuffleFlag,
+					precisionFlag,
+					resolutionFlag,
+					timeDeltaOrderFlag,
+					valueDeltaOrderFlag,
 					&cli.StringFlag{
-						Name:     "path",
-						Aliases:  []string{"p"},
-						Usage:    "path to an uncompressed data file",
-						Required: true,
+						Name:  "in",
+						Usage: "path to an uncompressed data file, or - for stdin",
+						Value: "-",
+					},
+					&cli.StringFlag{
+						Name:  "out",
+						Usage: "path to write the compressed data to, or - for stdout",
+						Value: "-",
 					},
 				},
 				Action: func(c *cli.Context) error {
-					algorithm := compress.Method(c.String("method"))
-					if !compress.AllMethods.Contains(algorithm) {
-						return fmt.Errorf("invalid method: %s. must be one of: %v", algorithm, compress.AllMethods.Strings())
+					algorithm, err := parseMethod(c.String("method"))
+					if err != nil {
+						return err
+					}
+					resolution, err := series.ParseResolution(c.String("resolution"))
+					if err != nil {
+						return err
 					}
 
-					evaluation, err := evaluate.NewEvaluation(algorithm, c.Bool("interleave"), c.String("path"))
+					dict, err := dictionary(c)
+					if err != nil {
+						return err
+					}
+					shuffle, err := compress.ParseShuffle(c.String("shuffle"))
 					if err != nil {
 						return err
 					}
 
-					result, err := evaluation.Run()
+					in, err := openInput(c.String("in"))
 					if err != nil {
 						return err
 					}
+					defer in.Close()
 
-					result.PrintStats()
+					points, err := series.FromReaderResolution(in, resolution)
+					if err != nil {
+						return err
+					}
 
-					return nil
+					compressor := compress.NewCompressorOptions(compress.Options{
+						Method:          algorithm,
+						Interleave:      c.Bool("interleave"),
+						Resolution:      resolution,
+						Precision:       c.Int64("precision"),
+						TimeDeltaOrder:  c.Int("time-delta-order"),
+						ValueDeltaOrder: c.Int("value-delta-order"),
+						Level:           level(c),
+						Dictionary:      dict,
+						Shuffle:         shuffle,
+					})
+					b, err := compressor.Compress(points)
+					if err != nil {
+						return err
+					}
+
+					return writeOutput(c.String("out"), func(w io.Writer) error {
+						_, err := w.Write(b)
+						return err
+					})
+				},
+			},
+			{
+				Name:  "decompress",
+				Usage: "decompress a file created by compress back to CSV",
+				Flags: []cli.Flag{
+					dictFlag,
+					&cli.StringFlag{
+						Name:  "in",
+						Usage: "path to a compressed file, or - for stdin",
+						Value: "-",
+					},
+					&cli.StringFlag{
+						Name:  "out",
+						Usage: "path to write the CSV to, or - for stdout",
+						Value: "-",
+					},
+				},
+				Action: func(c *cli.Context) error {
+					dict, err := dictionary(c)
+					if err != nil {
+						return err
+					}
+
+					in, err := openInput(c.String("in"))
+					if err != nil {
+						return err
+					}
+					defer in.Close()
+
+					b, err := io.ReadAll(in)
+					if err != nil {
+						return err
+					}
+
+					h, _, err := compress.ReadHeader(b)
+					if err != nil {
+						return err
+					}
+					points, err := compress.NewCompressorOptions(compress.Options{Dictionary: dict}).Decompress(b)
+					if err != nil {
+						return err
+					}
+
+					// Write timestamps in the unit they were compressed with.
+					return writeOutput(c.String("out"), func(w io.Writer) error {
+						return points.WriteCSVResolution(w, h.Resolution)
+					})
+				},
+			},
+			{
+				Name:  "train-dict",
+				Usage: "train a zstd dictionary for zstd-dict-csv from a directory of data files",
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:     "dir",
+						Usage:    "directory of uncompressed data files to train on",
+						Required: true,
+					},
+					&cli.StringFlag{
+						Name:  "out",
+						Usage: "path to write the dictionary to, or - for stdout",
+						Value: "-",
+					},
+					&cli.IntFlag{
+						Name:  "size",
+						Usage: "maximum dictionary size in bytes",
+						Value: compress.DefaultDictionarySize,
+					},
+					&cli.IntFlag{
+						Name:  "chunk-size",
+						Usage: "number of points per training sample. should match the size of the series that will be compressed",
+						Value: 128,
+					},
+					&cli.UintFlag{
+						Name:  "id",
+						Usage: "dictionary id stored in the compressed output. default: random",
+					},
+					&cli.BoolFlag{
+						Name:    "interleave",
+						Aliases: []string{"i"},
+						Usage:   "train on interleaved timestamps and values. must match compress. default: false",
+					},
+					precisionFlag,
+					resolutionFlag,
+					timeDeltaOrderFlag,
+					valueDeltaOrderFlag,
+				},
+				Action: func(c *cli.Context) error {
+					resolution, err := series.ParseResolution(c.String("resolution"))
+					if err != nil {
+						return err
+					}
+					if c.Int("chunk-size") <= 0 {
+						return fmt.Errorf("invalid chunk size: %d", c.Int("chunk-size"))
+					}
+
+					entries, err := os.ReadDir(c.String("dir"))
+					if err != nil {
+						return err
+					}
+					var all []series.Points
+					for _, entry := range entries {
+						if !entry.Type().IsRegular() {
+							continue
+						}
+						points, err := series.FromFileResolution(filepath.Join(c.String("dir"), entry.Name()), resolution)
+						if err != nil {
+							return fmt.Errorf("%s: %w", entry.Name(), err)
+						}
+						all = append(all, points)
+					}
+
+					samples := compress.DictionarySamples(all, compress.Options{
+						Interleave:      c.Bool("interleave"),
+						Resolution:      resolution,
+						Precision:       c.Int64("precision"),
+						TimeDeltaOrder:  c.Int("time-delta-order"),
+						ValueDeltaOrder: c.Int("value-delta-order"),
+					}, c.Int("chunk-size"))
+					dict, err := compress.TrainDictionary(samples, c.Int("size"), uint32(c.Uint("id")))
+					if err != nil {
+						return err
+					}
+					log.Printf("trained dictionary %d (%d bytes) from %d samples", dict.ID, len(dict.Content), len(samples))
+
+					return writeOutput(c.String("out"), func(w io.Writer) error {
+						_, err := w.Write(dict.Content)
+						return err
+					})
 				},
 			},
 		},
@@ -65,3 +458,155 @@ func main() {
 		log.Fatal(err)
 	}
 }
+
+var precisionFlag = &cli.Int64Flag{
+	Name:  "precision",
+	Usage: "integer steps per unit that values are stored with, e.g. 1000 for milli-units or 1 for whole units",
+	Value: series.MilliPrecision,
+}
+
+var resolutionFlag = &cli.StringFlag{
+	Name:  "resolution",
+	Usage: "unit of the input timestamps, which are stored and delta encoded in the same unit. one of: s, ms, us, ns",
+	Value: series.Millisecond.String(),
+}
+
+var timeDeltaOrderFlag = &cli.IntFlag{
+	Name:  "time-delta-order",
+	Usage: fmt.Sprintf("how many times timestamps are differenced by integer methods. 2 is delta-of-delta. at most %d", compress.MaxDeltaOrder),
+	Value: 1,
+}
+
+var valueDeltaOrderFlag = &cli.IntFlag{
+	Name:  "value-delta-order",
+	Usage: fmt.Sprintf("how many times values are differenced by integer methods. at most %d", compress.MaxDeltaOrder),
+	Value: 1,
+}
+
+var verifyFlag = &cli.StringFlag{
+	Name:  "verify",
+	Usage: "check that the compressed data decodes to the original. one of: off, on (--resolution and --precision), strict (exact)",
+	Value: compress.VerifyOn.String(),
+}
+
+var formatFlag = &cli.StringFlag{
+	Name:    "format",
+	Aliases: []string{"f"},
+	Usage:   "output format. one of: text, json, csv, markdown",
+	Value:   string(evaluate.FormatText),
+}
+
+var warmupFlag = &cli.IntFlag{
+	Name:  "warmup",
+	Usage: "number of untimed iterations to run before measuring",
+}
+
+var iterationsFlag = &cli.IntFlag{
+	Name:  "iterations",
+	Usage: "number of timed encode and decode iterations. the minimum if --bench-time is set",
+	Value: 1,
+}
+
+var benchTimeFlag = &cli.DurationFlag{
+	Name:  "bench-time",
+	Usage: "run enough iterations to take roughly this long, e.g. 1.5s",
+}
+
+// benchmark returns the benchmark described by the --warmup, --iterations and
+// --bench-time flags.
+func benchmark(c *cli.Context) evaluate.Benchmark {
+	return evaluate.Benchmark{
+		Warmup:     c.Int("warmup"),
+		Iterations: c.Int("iterations"),
+		Duration:   c.Duration("bench-time"),
+	}
+}
+
+var levelFlag = &cli.IntFlag{
+	Name:    "level",
+	Aliases: []string{"l"},
+	Usage:   "compression level of " + levelMethods() + ". default: the method's default",
+}
+
+// levelMethods describes the level range of each method that has one.
+func levelMethods() string {
+	var methods []string
+	for _, method := range compress.AllMethods {
+		if levels, ok := compress.MethodLevels(method); ok {
+			methods = append(methods, fmt.Sprintf("%s (%s)", method, levels))
+		}
+	}
+	return strings.Join(methods, ", ")
+}
+
+var shuffleFlag = &cli.StringFlag{
+	Name:  "shuffle",
+	Usage: "group the bytes or bits of fixed-width integers before compressing. only applies to the *-fixed methods. one of: none, byte, bit",
+	Value: compress.ShuffleNone.String(),
+}
+
+var dictFlag = &cli.StringFlag{
+	Name:  "dict",
+	Usage: "path to a dictionary from train-dict, used by zstd-dict-csv",
+}
+
+// dictionary reads the --dict flag, or returns nil if it is not set.
+func dictionary(c *cli.Context) (*compress.Dictionary, error) {
+	if !c.IsSet("dict") {
+		return nil, nil
+	}
+	b, err := os.ReadFile(c.String("dict"))
+	if err != nil {
+		return nil, err
+	}
+	return compress.ParseDictionary(b)
+}
+
+// level returns the --level flag, or nil if it is not set.
+func level(c *cli.Context) *int {
+	if !c.IsSet("level") {
+		return nil
+	}
+	return compress.Level(c.Int("level"))
+}
+
+// parseMethod returns the registered method or pipeline (see
+// compress.Pipeline) named name.
+func parseMethod(name string) (compress.Method, error) {
+	method := compress.Method(name)
+	if compress.AllMethods.Contains(method) {
+		return method, nil
+	}
+	if strings.Contains(name, compress.PipelineSeparator) {
+		if _, err := compress.ParsePipeline(name); err != nil {
+			return "", err
+		}
+		return method, nil
+	}
+	return "", fmt.Errorf("invalid method: %s. must be one of: %v, or a pipeline such as delta|csv|zstd:19", method, compress.AllMethods.Strings())
+}
+
+// openInput opens path for reading, or stdin if path is "-".
+func openInput(path string) (io.ReadCloser, error) {
+	if path == "-" {
+		return io.NopCloser(os.Stdin), nil
+	}
+	return os.Open(path)
+}
+
+// writeOutput calls write with path opened for writing, or stdout if path is "-".
+func writeOutput(path string, write func(io.Writer) error) error {
+	if path == "-" {
+		return write(os.Stdout)
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := write(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -3,6 +3,7 @@ package series
 import (
 	"encoding/csv"
 	"fmt"
+	"io"
 	"math"
 	"os"
 	"strconv"
@@ -10,103 +11,169 @@ import (
 )
 
 type Point struct {
-	Time  time.Time
-	Value float32
+	Time time.Time
+	// Value is a float64, not a float32, so values with more than 7
+	// significant digits round trip.
+	Value float64
+}
+
+// MilliPrecision stores values as thousandths, e.g. milligrams for a series
+// measured in grams. It is the default precision.
+const MilliPrecision = 1000
+
+// ValueScaled returns the value as an integer multiple of 1/precision.
+func (p *Point) ValueScaled(precision int64) int64 {
+	return int64(math.Round(p.Value * float64(precision)))
 }
 
 func (p *Point) ValueMilli() int64 {
-	return int64(math.Round(float64(p.Value * 1000)))
+	return p.ValueScaled(MilliPrecision)
 }
 
 func (p *Point) TimeMilli() int64 {
-	return p.Time.UnixMilli()
+	return p.TimeUnix(Millisecond)
+}
+
+// TimeUnix returns the time as the number of resolution units since the
+// Unix epoch.
+func (p *Point) TimeUnix(resolution Resolution) int64 {
+	return resolution.Unix(p.Time)
 }
 
 func (p *Point) MilliEqual(other *Point) bool {
+	return p.ScaledEqual(other, Millisecond, MilliPrecision)
+}
+
+// ScaledEqual reports whether both points have the same time at resolution
+// and the same value at precision.
+func (p *Point) ScaledEqual(other *Point, resolution Resolution, precision int64) bool {
 	if p == nil || other == nil {
 		return p == nil && other == nil
 	}
-	return p.TimeMilli() == other.TimeMilli() && p.ValueMilli() == other.ValueMilli()
+	return p.TimeUnix(resolution) == other.TimeUnix(resolution) && p.ValueScaled(precision) == other.ValueScaled(precision)
+}
+
+// Equal reports whether both points have exactly the same time and value.
+func (p *Point) Equal(other *Point) bool {
+	if p == nil || other == nil {
+		return p == nil && other == nil
+	}
+	return p.Time.Equal(other.Time) && math.Float64bits(p.Value) == math.Float64bits(other.Value)
 }
 
 type Points []*Point
 
 func (p Points) MilliEqual(other Points) bool {
-	if len(p) != len(other) {
-		return false
-	}
+	return p.Mismatch(other, (*Point).MilliEqual) < 0
+}
+
+func (p Points) Equal(other Points) bool {
+	return p.Mismatch(other, (*Point).Equal) < 0
+}
+
+// Mismatch returns the index of the first point where p and other differ
+// according to equal, or -1 if they are the same. If one is a prefix of the
+// other, the length of the shorter one is returned.
+func (p Points) Mismatch(other Points, equal func(a, b *Point) bool) int {
 	for i := range p {
-		if !p[i].MilliEqual(other[i]) {
-			fmt.Printf("p[%d] = %v, other[%d] = %v\n", i, p[i], i, other[i])
-			return false
+		if i >= len(other) || !equal(p[i], other[i]) {
+			return i
 		}
 	}
-	return true
+	if len(other) > len(p) {
+		return len(p)
+	}
+	return -1
 }
 
-func (p Points) Flatten(interleaved bool) []uint64 {
+// Flatten returns Interleaved or Split. Times are stored in resolution units
+// and values as integer multiples of 1/precision.
+func (p Points) Flatten(interleaved bool, resolution Resolution, precision int64) []uint64 {
 	if interleaved {
-		return p.Interleaved()
+		return p.Interleaved(resolution, precision)
 	}
-	return p.Split()
+	return p.Split(resolution, precision)
 }
 
 // Interleaved returns an int64 array with alternating timestamps and values.
-func (p Points) Interleaved() []uint64 {
+func (p Points) Interleaved(resolution Resolution, precision int64) []uint64 {
 	interleaved := make([]uint64, len(p)*2)
 	for i, pt := range p {
-		interleaved[i*2] = uint64(pt.TimeMilli())
+		interleaved[i*2] = uint64(pt.TimeUnix(resolution))
 		// Use ZigZag encoding to shrink the number of set bits without losing sign information.
-		interleaved[i*2+1] = ZigZagEncode64(pt.ValueMilli())
+		interleaved[i*2+1] = ZigZagEncode64(pt.ValueScaled(precision))
 	}
 
 	return interleaved
 }
 
 // Split returns an int64 array with timestamps first followed by values after.
-func (p Points) Split() []uint64 {
+func (p Points) Split(resolution Resolution, precision int64) []uint64 {
 	split := make([]uint64, len(p)*2)
 	for i, pt := range p {
-		split[i] = uint64(pt.TimeMilli())
+		split[i] = uint64(pt.TimeUnix(resolution))
 		// Use ZigZag encoding to shrink the number of set bits without losing sign information.
-		split[i+len(p)] = ZigZagEncode64(pt.ValueMilli())
+		split[i+len(p)] = ZigZagEncode64(pt.ValueScaled(precision))
 	}
 
 	return split
 }
 
-func FromFlat(flat []uint64, interleaved bool) Points {
+// FromFlat reverses Flatten.
+func FromFlat(flat []uint64, interleaved bool, resolution Resolution, precision int64) Points {
 	if interleaved {
-		return FromInterleaved(flat)
+		return FromInterleaved(flat, resolution, precision)
 	}
-	return FromSplit(flat)
+	return FromSplit(flat, resolution, precision)
 }
 
-func FromInterleaved(interleaved []uint64) Points {
+func FromInterleaved(interleaved []uint64, resolution Resolution, precision int64) Points {
 	pts := make(Points, len(interleaved)/2)
 	for i := 0; i < len(interleaved)/2; i++ {
 		pts[i] = &Point{
-			Time:  time.UnixMilli(int64(interleaved[i*2])),
-			Value: float32(ZigZagDecode64(interleaved[i*2+1])) / 1000,
+			Time:  resolution.Time(int64(interleaved[i*2])),
+			Value: float64(ZigZagDecode64(interleaved[i*2+1])) / float64(precision),
 		}
 	}
 
 	return pts
 }
 
-func FromSplit(split []uint64) Points {
+func FromSplit(split []uint64, resolution Resolution, precision int64) Points {
 	pts := make(Points, len(split)/2)
 	for i := 0; i < len(split)/2; i++ {
 		pts[i] = &Point{
-			Time:  time.UnixMilli(int64(split[i])),
-			Value: float32(ZigZagDecode64(split[i+len(split)/2])) / 1000,
+			Time:  resolution.Time(int64(split[i])),
+			Value: float64(ZigZagDecode64(split[i+len(split)/2])) / float64(precision),
 		}
 	}
 
 	return pts
 }
 
+// Rounded returns the points with times truncated to resolution and values
+// rounded to the nearest multiple of 1/precision. Rounding before
+// DeltaEncoded keeps small deltas from being lost when they are scaled.
+func (p Points) Rounded(resolution Resolution, precision int64) Points {
+	rounded := make(Points, len(p))
+	for i, pt := range p {
+		rounded[i] = &Point{
+			Time:  resolution.Time(pt.TimeUnix(resolution)),
+			Value: float64(pt.ValueScaled(precision)) / float64(precision),
+		}
+	}
+	return rounded
+}
+
+// DeltaEncoded returns DeltaEncodedResolution with millisecond deltas.
 func (p Points) DeltaEncoded(times bool, values bool) Points {
+	return p.DeltaEncodedResolution(times, values, Millisecond)
+}
+
+// DeltaEncodedResolution replaces every point after the first with its
+// difference from the previous point. Time deltas are truncated to
+// resolution and stored as a time that many units after the Unix epoch.
+func (p Points) DeltaEncodedResolution(times bool, values bool, resolution Resolution) Points {
 	enc := make(Points, len(p))
 	enc[0] = p[0]
 
@@ -114,7 +181,7 @@ func (p Points) DeltaEncoded(times bool, values bool) Points {
 		enc[i] = &Point{}
 		if times {
 			timeDelta := p[i].Time.Sub(p[i-1].Time)
-			enc[i].Time = time.UnixMilli(timeDelta.Milliseconds())
+			enc[i].Time = resolution.Time(int64(timeDelta / time.Duration(resolution)))
 		} else {
 			enc[i].Time = p[i].Time
 		}
@@ -148,40 +215,163 @@ func (p Points) DeltaDecoded(times bool, values bool) Points {
 	return dec
 }
 
+// DeltaOfDeltaEncoded returns DeltaEncodedOrder with second order,
+// millisecond differences. Near regular timestamps become mostly zero.
+func (p Points) DeltaOfDeltaEncoded(times bool, values bool) Points {
+	return p.DeltaEncodedOrder(2, times, values, Millisecond)
+}
+
+// DeltaOfDeltaDecoded reverses DeltaOfDeltaEncoded.
+func (p Points) DeltaOfDeltaDecoded(times bool, values bool) Points {
+	return p.DeltaDecodedOrder(2, times, values, Millisecond)
+}
+
+// DeltaEncodedOrder takes order successive differences of the points. The
+// first point is kept and point i < order holds the i-th order difference,
+// so order 0 returns a copy. Times are truncated to resolution units before
+// they are differenced and stored as a time that many units after the Unix
+// epoch. Unlike DeltaEncodedResolution, which truncates the differences of
+// the raw times, order 1 only matches it for times that are already whole
+// resolution units.
+func (p Points) DeltaEncodedOrder(order int, times bool, values bool, resolution Resolution) Points {
+	enc := p.unixPoints(times, resolution)
+	for k := 1; k <= order; k++ {
+		for i := len(enc) - 1; i >= k; i-- {
+			if times {
+				enc[i].unix -= enc[i-1].unix
+			}
+			if values {
+				enc[i].Value -= enc[i-1].Value
+			}
+		}
+	}
+	return fromUnixPoints(enc, times, resolution)
+}
+
+// DeltaDecodedOrder reverses DeltaEncodedOrder.
+func (p Points) DeltaDecodedOrder(order int, times bool, values bool, resolution Resolution) Points {
+	dec := p.unixPoints(times, resolution)
+	for k := order; k >= 1; k-- {
+		for i := k; i < len(dec); i++ {
+			if times {
+				dec[i].unix += dec[i-1].unix
+			}
+			if values {
+				dec[i].Value += dec[i-1].Value
+			}
+		}
+	}
+	return fromUnixPoints(dec, times, resolution)
+}
+
+// unixPoint is a Point with its time in resolution units.
+type unixPoint struct {
+	Point
+	unix int64
+}
+
+func (p Points) unixPoints(times bool, resolution Resolution) []unixPoint {
+	pts := make([]unixPoint, len(p))
+	for i, pt := range p {
+		pts[i].Point = *pt
+		if times {
+			pts[i].unix = pt.TimeUnix(resolution)
+		}
+	}
+	return pts
+}
+
+func fromUnixPoints(pts []unixPoint, times bool, resolution Resolution) Points {
+	p := make(Points, len(pts))
+	for i := range pts {
+		pt := pts[i].Point
+		if times {
+			pt.Time = resolution.Time(pts[i].unix)
+		}
+		p[i] = &pt
+	}
+	return p
+}
+
 func FromFile(filename string) (Points, error) {
+	return FromFileResolution(filename, Millisecond)
+}
+
+// FromFileResolution reads a file in the format of FromReaderResolution.
+func FromFileResolution(filename string, resolution Resolution) (Points, error) {
 	f, err := os.Open(filename)
 	if err != nil {
 		return nil, err
 	}
+	defer f.Close()
+
+	return FromReaderResolution(f, resolution)
+}
+
+// FromReader parses a CSV of unix millisecond timestamps and values. The
+// first line is a header and is skipped.
+func FromReader(reader io.Reader) (Points, error) {
+	return FromReaderResolution(reader, Millisecond)
+}
 
-	r := csv.NewReader(f)
+// FromReaderResolution parses a CSV of unix timestamps in resolution units
+// and values. The first line is a header and is skipped.
+func FromReaderResolution(reader io.Reader, resolution Resolution) (Points, error) {
+	r := csv.NewReader(reader)
 	lines, err := r.ReadAll()
 	if err != nil {
 		return nil, err
 	}
+	if len(lines) == 0 {
+		return nil, fmt.Errorf("no data")
+	}
 
 	pts := make(Points, len(lines)-1)
 	for i, l := range lines[1:] {
-		unixMilli, err := strconv.ParseInt(l[0], 10, 64)
+		unix, err := strconv.ParseInt(l[0], 10, 64)
 		if err != nil {
 			return nil, err
 		}
-		t := time.UnixMilli(unixMilli)
+		t := resolution.Time(unix)
 
-		value, err := strconv.ParseFloat(l[1], 32)
+		value, err := strconv.ParseFloat(l[1], 64)
 		if err != nil {
 			return nil, err
 		}
 
 		pts[i] = &Point{
 			Time:  t,
-			Value: float32(value),
+			Value: value,
 		}
 	}
 
 	return pts, nil
 }
 
+// WriteCSV writes points in the format read by FromReader.
+func (p Points) WriteCSV(writer io.Writer) error {
+	return p.WriteCSVResolution(writer, Millisecond)
+}
+
+// WriteCSVResolution writes points in the format read by FromReaderResolution.
+func (p Points) WriteCSVResolution(writer io.Writer, resolution Resolution) error {
+	w := csv.NewWriter(writer)
+	if err := w.Write([]string{"timestamp", "weight"}); err != nil {
+		return err
+	}
+	for _, pt := range p {
+		err := w.Write([]string{
+			strconv.FormatInt(pt.TimeUnix(resolution), 10),
+			strconv.FormatFloat(pt.Value, 'f', -1, 64),
+		})
+		if err != nil {
+			return err
+		}
+	}
+	w.Flush()
+	return w.Error()
+}
+
 // https://github.com/jwilder/encoding/blob/master/bitops/bits.go#L66C1-L68C2
 func ZigZagEncode64(x int64) uint64 {
 	return uint64(uint64(x<<1) ^ uint64((int64(x) >> 63)))
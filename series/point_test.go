@@ -1,6 +1,7 @@
 package series
 
 import (
+	"bytes"
 	"fmt"
 	"testing"
 	"time"
@@ -24,7 +25,7 @@ func TestPoint_Interleaved(t *testing.T) {
 		},
 	}
 
-	interleaved := pts.Interleaved()
+	interleaved := pts.Interleaved(Millisecond, MilliPrecision)
 	// Values are converted to milli (*1000) and zigzag encoded.
 	expected := []uint64{
 		uint64(1_000),
@@ -53,7 +54,7 @@ func TestPoint_Split(t *testing.T) {
 		},
 	}
 
-	split := pts.Split()
+	split := pts.Split(Millisecond, MilliPrecision)
 	expected := []uint64{
 		uint64(1_000),
 		uint64(2_000),
@@ -82,12 +83,40 @@ func TestPoint_FromSplit(t *testing.T) {
 		},
 	}
 
-	split := pts.Split()
-	unsplit := FromSplit(split)
+	split := pts.Split(Millisecond, MilliPrecision)
+	unsplit := FromSplit(split, Millisecond, MilliPrecision)
 
 	assert.Equal(t, unsplit, pts)
 }
 
+func TestPoint_Precision(t *testing.T) {
+	pts := Points{
+		{
+			Time:  time.UnixMilli(1_000),
+			Value: 1.234567,
+		},
+		{
+			Time:  time.UnixMilli(2_000),
+			Value: -2,
+		},
+	}
+
+	assert.Equal(t, int64(1), pts[0].ValueScaled(1))
+	assert.Equal(t, int64(1_235), pts[0].ValueMilli())
+	assert.Equal(t, int64(1_234_567), pts[0].ValueScaled(1_000_000))
+
+	for _, precision := range []int64{1, MilliPrecision, 1_000_000} {
+		for _, interleaved := range []bool{false, true} {
+			flat := pts.Flatten(interleaved, Millisecond, precision)
+			assert.Equal(t, ZigZagEncode64(-2*precision), flat[len(flat)-1])
+
+			unflat := FromFlat(flat, interleaved, Millisecond, precision)
+			assert.Equal(t, -1, pts.Mismatch(unflat, func(a, b *Point) bool { return a.ScaledEqual(b, Millisecond, precision) }))
+		}
+	}
+	assert.False(t, pts[0].ScaledEqual(&Point{Time: pts[0].Time, Value: 1.2346}, Millisecond, 1_000_000))
+}
+
 func TestPoint_DeltaEncoded(t *testing.T) {
 	pts := Points{
 		{
@@ -135,6 +164,85 @@ func TestPoint_DeltaEncoded(t *testing.T) {
 	}
 }
 
+func TestPoint_Resolution(t *testing.T) {
+	pts := Points{
+		{
+			Time:  time.Unix(1_691_161_006, 379_123_456),
+			Value: 10,
+		},
+		{
+			Time:  time.Unix(1_691_161_007, 379_123_999),
+			Value: 15,
+		},
+	}
+
+	for _, resolution := range AllResolutions {
+		parsed, err := ParseResolution(resolution.String())
+		assert.NoError(t, err)
+		assert.Equal(t, resolution, parsed)
+
+		rounded := pts.Rounded(resolution, MilliPrecision)
+		assert.Equal(t, pts[0].TimeUnix(resolution), rounded[0].TimeUnix(resolution))
+		assert.Equal(t, rounded[0].Time, resolution.Time(rounded[0].TimeUnix(resolution)))
+
+		deltas := rounded.DeltaEncodedResolution(true, true, resolution)
+		assert.Equal(t, pts[1].TimeUnix(resolution)-pts[0].TimeUnix(resolution), deltas[1].TimeUnix(resolution))
+		assert.True(t, rounded[1].Time.Equal(deltas.DeltaDecoded(true, true)[1].Time), "%s", resolution)
+
+		var buf bytes.Buffer
+		assert.NoError(t, rounded.WriteCSVResolution(&buf, resolution))
+		read, err := FromReaderResolution(&buf, resolution)
+		assert.NoError(t, err)
+		assert.True(t, rounded.Equal(read), "%s", resolution)
+	}
+	assert.Equal(t, int64(1_000_000_543), pts.DeltaEncodedResolution(true, false, Nanosecond)[1].TimeUnix(Nanosecond))
+
+	_, err := ParseResolution("m")
+	assert.Error(t, err)
+}
+
+func TestPoint_DeltaOfDeltaEncoded(t *testing.T) {
+	pts := Points{
+		{
+			Time:  time.UnixMilli(1_000),
+			Value: 10,
+		},
+		{
+			Time:  time.UnixMilli(1_100),
+			Value: 15,
+		},
+		{
+			Time:  time.UnixMilli(1_200),
+			Value: 25,
+		},
+		{
+			Time:  time.UnixMilli(1_301),
+			Value: 40,
+		},
+	}
+
+	dod := pts.DeltaOfDeltaEncoded(true, true)
+	expectedTimes := []int64{1_000, 100, 0, 1}
+	expectedValues := []float64{10, 5, 5, 5}
+	for i := range pts {
+		assert.Equal(t, expectedTimes[i], dod[i].TimeMilli())
+		assert.Equal(t, expectedValues[i], dod[i].Value)
+	}
+	assert.True(t, pts.Equal(dod.DeltaOfDeltaDecoded(true, true)))
+
+	// Values are left alone.
+	assert.Equal(t, pts[3].Value, pts.DeltaOfDeltaEncoded(true, false)[3].Value)
+
+	assert.True(t, pts.DeltaEncoded(true, true).Equal(pts.DeltaEncodedOrder(1, true, true, Millisecond)))
+	for order := 0; order <= 4; order++ {
+		for _, resolution := range AllResolutions {
+			enc := pts.DeltaEncodedOrder(order, true, true, resolution)
+			dec := enc.DeltaDecodedOrder(order, true, true, resolution)
+			assert.Equal(t, -1, pts.Mismatch(dec, func(a, b *Point) bool { return a.ScaledEqual(b, resolution, MilliPrecision) }), "order=%d resolution=%s", order, resolution)
+		}
+	}
+}
+
 func TestPoint_DeltaDecoded(t *testing.T) {
 	pts := Points{
 		{
@@ -165,6 +273,48 @@ func TestPoint_DeltaDecoded(t *testing.T) {
 	}
 }
 
+func TestPoints_Mismatch(t *testing.T) {
+	pts := Points{
+		{Time: time.UnixMilli(1_000), Value: 1},
+		{Time: time.UnixMilli(2_000), Value: 2},
+	}
+	close := Points{
+		{Time: time.UnixMilli(1_000), Value: 1},
+		{Time: time.UnixMilli(2_000), Value: 2.0001},
+	}
+
+	assert.Equal(t, -1, pts.Mismatch(close, (*Point).MilliEqual))
+	assert.Equal(t, 1, pts.Mismatch(close, (*Point).Equal))
+	assert.Equal(t, 1, pts.Mismatch(pts[:1], (*Point).Equal))
+	assert.Equal(t, 1, pts[:1].Mismatch(pts, (*Point).Equal))
+	assert.True(t, pts.Equal(pts))
+}
+
+func TestPoints_WriteCSV(t *testing.T) {
+	pts := Points{
+		{
+			Time:  time.UnixMilli(1691161006379),
+			Value: 17.52,
+		},
+		{
+			Time:  time.UnixMilli(1691161006394),
+			Value: -0.1,
+		},
+		{
+			Time:  time.UnixMilli(1691161006513),
+			Value: 35.319999999999986,
+		},
+	}
+
+	var buf bytes.Buffer
+	assert.NoError(t, pts.WriteCSV(&buf))
+	assert.Equal(t, "timestamp,weight\n1691161006379,17.52\n1691161006394,-0.1\n1691161006513,35.319999999999986\n", buf.String())
+
+	read, err := FromReader(&buf)
+	assert.NoError(t, err)
+	assert.Equal(t, pts, read)
+}
+
 func TestZigZagEncode(t *testing.T) {
 	nums := []int16{-22, -123, -350}
 	for _, n := range nums {
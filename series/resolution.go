@@ -0,0 +1,72 @@
+package series
+
+import (
+	"fmt"
+	"time"
+)
+
+// Resolution is the unit timestamps are stored in.
+type Resolution time.Duration
+
+const (
+	Second      = Resolution(time.Second)
+	Millisecond = Resolution(time.Millisecond)
+	Microsecond = Resolution(time.Microsecond)
+	Nanosecond  = Resolution(time.Nanosecond)
+)
+
+// AllResolutions lists the supported resolutions, coarsest first.
+var AllResolutions = []Resolution{Second, Millisecond, Microsecond, Nanosecond}
+
+var resolutionNames = map[Resolution]string{
+	Second:      "s",
+	Millisecond: "ms",
+	Microsecond: "us",
+	Nanosecond:  "ns",
+}
+
+func (r Resolution) String() string {
+	if name, ok := resolutionNames[r]; ok {
+		return name
+	}
+	return fmt.Sprintf("Resolution(%d)", int64(r))
+}
+
+// ParseResolution parses one of "s", "ms", "us" or "ns".
+func ParseResolution(s string) (Resolution, error) {
+	for _, r := range AllResolutions {
+		if resolutionNames[r] == s {
+			return r, nil
+		}
+	}
+	return 0, fmt.Errorf("invalid resolution: %s. must be one of: s, ms, us, ns", s)
+}
+
+// Unix returns t as the number of r units since the Unix epoch. Anything
+// finer than r is truncated.
+func (r Resolution) Unix(t time.Time) int64 {
+	switch r {
+	case Second:
+		return t.Unix()
+	case Microsecond:
+		return t.UnixMicro()
+	case Nanosecond:
+		return t.UnixNano()
+	default:
+		return t.UnixMilli()
+	}
+}
+
+// Time returns the time v r units after the Unix epoch.
+func (r Resolution) Time(v int64) time.Time {
+	switch r {
+	case Second:
+		return time.Unix(v, 0)
+	case Microsecond:
+		return time.UnixMicro(v)
+	case Nanosecond:
+		return time.Unix(0, v)
+	default:
+		return time.UnixMilli(v)
+	}
+}